@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Agents writes AGENTS.md, the root-level agent instructions file read by
+// OpenAI Codex and other coding agents
+type Agents struct{}
+
+// agentsFormat is the layout of AGENTS.md
+var agentsFormat = markdownFormat{
+	Header:         "# AGENTS.md\n\nInstructions for AI coding agents working in this project.\n\n",
+	GlobalHeading:  "## Global Instructions\n\n",
+	ContextHeading: "## Context-specific Instructions\n\n",
+	RuleLevel:      3,
+	GlobsLabel:     "File Patterns",
+}
+
+func (a *Agents) Name() string {
+	return "agents"
+}
+
+func (a *Agents) Capabilities() ToolConfig {
+	return ToolConfig{SupportsConditionalRules: false}
+}
+
+func (a *Agents) Build(config *ProjectConfig) error {
+	fmt.Fprintf(config.output(), "Building AGENTS.md configuration...\n")
+
+	agentsMdPath := filepath.Join(config.outputDir(a.Name()), "AGENTS.md")
+
+	output := buildCombinedMarkdown(config, a.Name(), agentsFormat)
+
+	if output == "" {
+		fmt.Fprintf(config.output(), "  ⚠ No rules found to generate AGENTS.md\n")
+		return config.noRules(a.Name())
+	}
+
+	if err := config.writer().MkdirAll(filepath.Dir(agentsMdPath), config.dirMode()); err != nil {
+		return fmt.Errorf("failed to create directory for AGENTS.md: %w", err)
+	}
+
+	err := config.writer().WriteFile(agentsMdPath, []byte(output), config.fileMode())
+	if err != nil {
+		return fmt.Errorf("failed to write AGENTS.md: %w", err)
+	}
+
+	fmt.Fprintf(config.output(), "  ✓ Generated AGENTS.md\n")
+	return nil
+}
+
+func (a *Agents) Import(ctx context.Context, rootPath string) (*ProjectConfig, error) {
+	config := &ProjectConfig{
+		RootPath: rootPath,
+	}
+
+	agentsMdPath := filepath.Join(rootPath, "AGENTS.md")
+	if data, err := os.ReadFile(agentsMdPath); err == nil {
+		config.CursorRules, config.MdcFiles = splitCombinedMarkdown(string(data), agentsFormat)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,223 @@
+package tools
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testConfig returns a project with global rules and one conditional rule,
+// loaded in memory without reading the tree at root
+func testConfig(root string) *ProjectConfig {
+	return &ProjectConfig{
+		RootPath:    root,
+		CursorRules: "Use tabs.\n",
+		RulesDir:    DefaultRulesDir,
+		Settings:    &Settings{},
+		MdcFiles: []MdcFile{{
+			Path:        filepath.Join(root, ".cursor", "rules", "react.mdc"),
+			Name:        "react",
+			Description: "React rules",
+			Globs:       []string{"**/*.tsx"},
+			RootGlobs:   []string{"**/*.tsx"},
+			Content:     "Use hooks.\n",
+			BaseDir:     root,
+		}},
+	}
+}
+
+func TestBuildInMemory(t *testing.T) {
+	tests := []struct {
+		tool    string
+		options BuildOptions
+		// want is the content of each written file, by path relative to the root
+		want map[string]string
+	}{
+		{
+			tool: "cursor",
+			want: map[string]string{},
+		},
+		{
+			tool:    "cursor",
+			options: BuildOptions{ToolVersions: map[string]string{"cursor": CursorLegacy}, ToolDirs: map[string]string{"cursor": "dist"}},
+			want: map[string]string{
+				"dist/.cursorrules": "# Global Rules\n\nUse tabs.\n\n# Context-specific Rules\n\n## React rules\n**Applies to:** **/*.tsx\n\nUse hooks.\n\n",
+			},
+		},
+		{
+			tool: "windsurf",
+			want: map[string]string{
+				".windsurfrules": "# Global Rules\nUse tabs.\n\n# Context-specific Rules\n\n## React rules\n**Applies to:** **/*.tsx\n\nUse hooks.\n\n",
+			},
+		},
+		{
+			tool: "roo-code",
+			want: map[string]string{
+				".roocode/global.md":      "# Global Context\n\nUse tabs.\n",
+				".roocode/React_rules.md": "---\ndescription: React rules\nglobs: [\"**/*.tsx\"]\nalwaysApply: false\n---\n\n# React rules\n\nUse hooks.\n",
+			},
+		},
+		{
+			tool: "cline",
+			want: map[string]string{
+				".clinerules": "# Global Instructions\n\nUse tabs.\n\n# Context-specific Instructions\n\n## React rules\n**File Patterns:** **/*.tsx\n\nUse hooks.\n\n",
+			},
+		},
+		{
+			tool: "claude-code",
+			want: map[string]string{
+				"CLAUDE.md": "# Claude Code Instructions\n\nThis file contains custom instructions for Claude Code.\n\n## Global Instructions\n\nUse tabs.\n\n## Context-specific Instructions\n\n### React rules\n**File Patterns:** **/*.tsx\n\nUse hooks.\n\n",
+			},
+		},
+		{
+			tool: "agents",
+			want: map[string]string{
+				"AGENTS.md": "# AGENTS.md\n\nInstructions for AI coding agents working in this project.\n\n## Global Instructions\n\nUse tabs.\n\n## Context-specific Instructions\n\n### React rules\n**File Patterns:** **/*.tsx\n\nUse hooks.\n\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		name := tt.tool
+		if version := tt.options.ToolVersions[tt.tool]; version != "" {
+			name += "/" + version
+		}
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			config := testConfig(root)
+			config.Options = tt.options
+			tool, err := createTool(tt.tool)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			memory, err := buildInMemory(config, tool)
+			if err != nil {
+				t.Fatalf("build failed: %v", err)
+			}
+
+			got := map[string]string{}
+			for path, data := range memory.Files() {
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[filepath.ToSlash(rel)] = string(data)
+			}
+			for path, want := range tt.want {
+				if got[path] != want {
+					t.Errorf("%s:\ngot  %q\nwant %q", path, got[path], want)
+				}
+			}
+			for path := range got {
+				if _, ok := tt.want[path]; !ok {
+					t.Errorf("unexpected file %s", path)
+				}
+			}
+
+			entries, err := os.ReadDir(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) > 0 {
+				t.Errorf("build wrote %d entries to disk", len(entries))
+			}
+		})
+	}
+}
+
+func TestMaxTokensTrimsLowestPriorityFirst(t *testing.T) {
+	root := t.TempDir()
+	rule := func(dir, name, level string) MdcFile {
+		return MdcFile{
+			Path:        filepath.Join(root, dir, ".cursor", "rules", name+".mdc"),
+			Name:        name,
+			Description: name + " rules",
+			Globs:       []string{"*.ts"},
+			RootGlobs:   []string{filepath.ToSlash(filepath.Join(dir, "*.ts"))},
+			Content:     "Follow the " + name + " conventions.\n",
+			BaseDir:     filepath.Join(root, dir),
+			Level:       level,
+		}
+	}
+
+	for _, sortRules := range []string{SortPath, SortName, SortNone} {
+		t.Run(sortRules, func(t *testing.T) {
+			config := testConfig(root)
+			// The required rule is placed after the optional one, whatever
+			// the sort: it is in a deeper folder and sorts later by name
+			config.MdcFiles = []MdcFile{rule(".", "alpha", LevelMay), rule("web", "zulu", LevelMust)}
+			config.Options.SortRules = sortRules
+			config.Output = io.Discard
+			full := buildFiles(t, config, "claude-code")["CLAUDE.md"]
+			if strings.Index(full, "alpha rules") > strings.Index(full, "zulu rules") {
+				t.Fatalf("optional rule isn't placed first:\n%s", full)
+			}
+
+			config.Options.MaxTokens = estimateTokens(full) - 1
+			trimmed := buildFiles(t, config, "claude-code")["CLAUDE.md"]
+			if strings.Contains(trimmed, "alpha rules") {
+				t.Errorf("optional rule kept over budget:\n%s", trimmed)
+			}
+			if !strings.Contains(trimmed, "zulu rules") {
+				t.Errorf("required rule trimmed before the optional one:\n%s", trimmed)
+			}
+		})
+	}
+}
+
+func TestDedupeContentKeepsXMLTags(t *testing.T) {
+	root := t.TempDir()
+	config := testConfig(root)
+	config.Options.Style = StyleXML
+	config.Options.DedupeContent = true
+	rule := func(name string, examples []string) MdcFile {
+		return MdcFile{
+			Path:        filepath.Join(root, ".cursor", "rules", name+".mdc"),
+			Name:        name,
+			Description: name + " rules",
+			Globs:       []string{"*.tsx"},
+			RootGlobs:   []string{"*.tsx"},
+			Content:     "Use hooks.\n",
+			BaseDir:     root,
+			Examples:    examples,
+		}
+	}
+	// The first two end with the same examples, so their closing tags stand
+	// alone after a repeated paragraph
+	config.MdcFiles = []MdcFile{
+		rule("react", []string{"useState over classes"}),
+		rule("preact", []string{"useState over classes"}),
+		rule("solid", nil),
+	}
+
+	claude := buildFiles(t, config, "claude-code")["CLAUDE.md"]
+	if got := strings.Count(claude, "Use hooks."); got != 1 {
+		t.Errorf("repeated rule body kept %d times, want once:\n%s", got, claude)
+	}
+	if opened, closed := strings.Count(claude, "<rule "), strings.Count(claude, "</rule>"); opened != 3 || closed != 3 {
+		t.Errorf("got %d opening and %d closing rule tags, want 3 of each:\n%s", opened, closed, claude)
+	}
+	decoder := xml.NewDecoder(strings.NewReader("<root>" + claude + "</root>"))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid XML: %v\n%s", err, claude)
+		}
+	}
+}
+
+func TestCapabilitySummaryUsesToolVersion(t *testing.T) {
+	config := testConfig(t.TempDir())
+	if got := config.capabilitySummary("cursor"); !strings.Contains(got, "conditional ✓") {
+		t.Errorf("default cursor format: got %q, want conditional rules supported", got)
+	}
+	config.Options.ToolVersions = map[string]string{"cursor": CursorLegacy}
+	if got := config.capabilitySummary("cursor"); !strings.Contains(got, "conditional ✗") {
+		t.Errorf("legacy cursor format: got %q, want conditional rules inlined", got)
+	}
+}
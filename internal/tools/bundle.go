@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Bundle packages the project's rule sources into a zip archive at outPath:
+// .cursorrules, the .cursorrules.d fragments and the rule files of every
+// rules directory, such as .cursor/rules, each stored by its path relative to the project
+// root. The archive can be unpacked into another project with ExtractBundle.
+func Bundle(outPath string, opts BuildOptions) error {
+	opts.readOnly = true
+	config, warnings, err := loadProjectConfig(opts)
+	if err != nil {
+		return fmt.Errorf("failed to load project config: %w", err)
+	}
+	reportWarnings(os.Stderr, warnings)
+
+	names, err := bundleFiles(config)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return ErrNoRules
+	}
+
+	// Write next to the destination, then rename it into place, so a failed
+	// bundle never leaves a truncated archive behind
+	absOut, err := filepath.Abs(outPath)
+	if err != nil {
+		return fmt.Errorf("invalid output path: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(absOut), ".bundle-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	archive := zip.NewWriter(tmp)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(config.RootPath, filepath.FromSlash(name)))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", name, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to add %s: %w", name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), absOut); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	fmt.Fprintf(config.output(), "  ✓ Bundled %d rule files into %s\n", len(names), outPath)
+	return nil
+}
+
+// bundleFiles returns the rule sources of a project by their slash-separated
+// path relative to the project root, sorted
+func bundleFiles(config *ProjectConfig) ([]string, error) {
+	names := []string{}
+	add := func(filePath string) {
+		rel, err := filepath.Rel(config.RootPath, filePath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return
+		}
+		names = append(names, filepath.ToSlash(rel))
+	}
+
+	if _, err := os.Stat(filepath.Join(config.RootPath, ".cursorrules")); err == nil {
+		add(filepath.Join(config.RootPath, ".cursorrules"))
+	}
+	fragments, _ := filepath.Glob(filepath.Join(config.RootPath, ".cursorrules.d", "*.md"))
+	for _, fragment := range fragments {
+		add(fragment)
+	}
+
+	extensions := config.Settings.ruleExtensions()
+	for _, rulesDir := range config.RulesDirs {
+		err := filepath.Walk(rulesDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() && hasRuleExtension(path, extensions) {
+				add(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk rules directory %s: %w", rulesDir, err)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// ExtractBundle unpacks a rules bundle created by Bundle into the project.
+// Entries that aren't rule sources are skipped. Existing files with different
+// content are only replaced when force is set; otherwise nothing is written.
+func ExtractBundle(bundlePath string, force bool, opts BuildOptions) error {
+	config, _, err := loadProjectConfig(opts)
+	if err != nil {
+		return fmt.Errorf("failed to load project config: %w", err)
+	}
+
+	archive, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer archive.Close()
+
+	type entry struct {
+		name    string
+		data    []byte
+		replace bool
+	}
+	entries := []entry{}
+	conflicts := []string{}
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		name := path.Clean(file.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("bundle entry %s is outside the project root", file.Name)
+		}
+		if !isRulesArchiveEntry(name, config.rulesDir()) {
+			fmt.Fprintf(config.output(), "  ⚠ Skipped %s, which is not a rule file\n", name)
+			continue
+		}
+
+		data, err := readZipFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+		existing, err := os.ReadFile(filepath.Join(config.RootPath, filepath.FromSlash(name)))
+		if err == nil && bytes.Equal(existing, data) {
+			continue
+		}
+		if err == nil && !force {
+			conflicts = append(conflicts, name)
+		}
+		entries = append(entries, entry{name: name, data: data, replace: err == nil})
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("bundle would replace existing files with different content (use --force to replace them): %s", strings.Join(conflicts, ", "))
+	}
+
+	for _, e := range entries {
+		target := filepath.Join(config.RootPath, filepath.FromSlash(e.name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", e.name, err)
+		}
+		if err := os.WriteFile(target, e.data, 0644); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", e.name, err)
+		}
+		if e.replace {
+			fmt.Fprintf(config.output(), "  ✓ Replaced %s\n", e.name)
+		} else {
+			fmt.Fprintf(config.output(), "  ✓ Extracted %s\n", e.name)
+		}
+	}
+	if len(entries) == 0 {
+		fmt.Fprintf(config.output(), "  ✓ All rules in the bundle are already up to date\n")
+		return nil
+	}
+	fmt.Fprintf(config.output(), "  → Run 'syncai build' to regenerate configurations\n")
+	return nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CacheDirName is the directory, relative to the project root, where syncai
+// keeps data it can recreate. It is laid out as:
+//
+//	.syncai/cache/
+//	  remote/<hash>/   rules fetched with --include-root and --include-repo,
+//	                   one directory per source keyed by a hash of its URL
+//	  rules.gob        parsed rule files, one entry per file keyed by its
+//	                   path and checked against its size, modification time
+//	                   and, when those differ, a hash of its content
+//
+// Directories are created only when something is written to them, and the
+// whole cache can be deleted at any time; the next build fetches what it needs.
+const CacheDirName = ".syncai/cache"
+
+// CacheDir returns the cache directory of the project at rootPath. The
+// directory is not created.
+func CacheDir(rootPath string) string {
+	return filepath.Join(rootPath, filepath.FromSlash(CacheDirName))
+}
+
+// cacheDir returns the directory to cache data of the given kind in. With
+// --no-cache a temporary directory is used instead, and the returned cleanup
+// function removes it; otherwise cleanup does nothing.
+func (c *ProjectConfig) cacheDir(kind string) (string, func(), error) {
+	if !c.Options.NoCache {
+		return filepath.Join(CacheDir(c.RootPath), kind), func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "syncai-"+kind+"-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
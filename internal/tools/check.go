@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrOutdated is returned by Check when generated files differ from what a
+// build would write
+var ErrOutdated = errors.New("generated files are out of date")
+
+// Check validates the rules and verifies that the files generated for each
+// tool are up to date, printing a line per problem and a summary. Rules that
+// fail to validate, including rules skipped with an error while loading, give
+// an InvalidError; otherwise generated files that are missing or differ give
+// ErrOutdated. No files are written.
+func Check(targets []string, opts BuildOptions) error {
+	opts.readOnly = true
+	config, err := loadBuildConfig(opts)
+	if err != nil {
+		return err
+	}
+	out := config.output()
+
+	fmt.Fprintf(out, "Validating rules...\n")
+	reportWarnings(out, config.Warnings)
+	problems, err := validateRules(config)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(out, "  ✗ %s\n", problem)
+	}
+	for _, warning := range config.Warnings {
+		if warning.Severity == SeverityError {
+			problems = append(problems, warning.String())
+		}
+	}
+	if len(problems) == 0 {
+		fmt.Fprintf(out, "  ✓ No problems found\n")
+	}
+
+	tools, err := resolveTools(config, targets)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Checking generated files...\n")
+	outdated := 0
+	for _, tool := range tools {
+		stale, err := outdatedFiles(config, tool)
+		if err != nil && !errors.Is(err, ErrNoRules) {
+			return fmt.Errorf("failed to build %s: %w", tool.Name(), err)
+		}
+		for _, line := range stale {
+			fmt.Fprintf(out, "  ✗ %s: %s\n", tool.Name(), line)
+		}
+		if len(stale) == 0 {
+			fmt.Fprintf(out, "  ✓ %s is up to date\n", tool.Name())
+		}
+		outdated += len(stale)
+	}
+
+	fmt.Fprintf(out, "%d problem(s), %d generated file(s) out of date\n", len(problems), outdated)
+	switch {
+	case len(problems) > 0:
+		return &InvalidError{Err: fmt.Errorf("found %d problem(s)", len(problems))}
+	case outdated > 0:
+		return fmt.Errorf("%w: run syncai build to update %d file(s)", ErrOutdated, outdated)
+	}
+	return nil
+}
+
+// outdatedFiles builds a tool in memory and describes each generated file that
+// is missing on disk or has different content
+func outdatedFiles(config *ProjectConfig, tool AITool) ([]string, error) {
+	memory, err := buildInMemory(config, tool)
+	if err != nil {
+		return nil, err
+	}
+
+	stale := []string{}
+	for _, path := range memory.Paths() {
+		relPath, _ := filepath.Rel(config.outputRoot(), path)
+		relPath = filepath.ToSlash(relPath)
+		expected, _ := memory.ReadFile(path)
+		actual, err := os.ReadFile(path)
+		switch {
+		case err != nil:
+			stale = append(stale, fmt.Sprintf("%s is missing", relPath))
+		case !bytes.Equal(actual, expected):
+			stale = append(stale, fmt.Sprintf("%s is out of date", relPath))
+		}
+	}
+	return stale, nil
+}
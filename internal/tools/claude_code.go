@@ -1,71 +1,58 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
 type ClaudeCode struct{}
 
+// claudeCodeFormat is the layout of CLAUDE.md
+var claudeCodeFormat = markdownFormat{
+	Header:         "# Claude Code Instructions\n\nThis file contains custom instructions for Claude Code.\n\n",
+	GlobalHeading:  "## Global Instructions\n\n",
+	ContextHeading: "## Context-specific Instructions\n\n",
+	RuleLevel:      3,
+	GlobsLabel:     "File Patterns",
+}
+
 func (c *ClaudeCode) Name() string {
 	return "claude-code"
 }
 
+func (c *ClaudeCode) Capabilities() ToolConfig {
+	return ToolConfig{SupportsConditionalRules: false}
+}
+
 func (c *ClaudeCode) Build(config *ProjectConfig) error {
-	fmt.Printf("Building Claude Code configuration...\n")
+	fmt.Fprintf(config.output(), "Building Claude Code configuration...\n")
 	
 	// Claude Code uses CLAUDE.md file
-	claudeMdPath := filepath.Join(config.RootPath, "CLAUDE.md")
+	claudeMdPath := filepath.Join(config.outputDir(c.Name()), "CLAUDE.md")
 	
-	var content strings.Builder
-	
-	// Add header
-	content.WriteString("# Claude Code Instructions\n\n")
-	content.WriteString("This file contains custom instructions for Claude Code.\n\n")
-	
-	// Add global rules from .cursorrules
-	if config.CursorRules != "" {
-		content.WriteString("## Global Instructions\n\n")
-		content.WriteString(config.CursorRules)
-		content.WriteString("\n\n")
-	}
+	output := buildCombinedMarkdown(config, c.Name(), claudeCodeFormat)
 	
-	// Add MDC files content
-	if len(config.MdcFiles) > 0 {
-		content.WriteString("## Context-specific Instructions\n\n")
-		for _, mdcFile := range config.MdcFiles {
-			if mdcFile.Description != "" {
-				content.WriteString(fmt.Sprintf("### %s\n", mdcFile.Description))
-			}
-			if len(mdcFile.Globs) > 0 {
-				content.WriteString(fmt.Sprintf("**File Patterns:** %s\n", strings.Join(mdcFile.Globs, ", ")))
-			}
-			if mdcFile.AlwaysApply {
-				content.WriteString("**Always Apply:** Yes\n")
-			}
-			content.WriteString("\n")
-			content.WriteString(mdcFile.Content)
-			content.WriteString("\n\n")
-		}
+	if output == "" {
+		fmt.Fprintf(config.output(), "  ⚠ No rules found to generate Claude Code configuration\n")
+		return config.noRules(c.Name())
 	}
 	
-	if config.CursorRules == "" && len(config.MdcFiles) == 0 {
-		fmt.Printf("  ⚠ No rules found to generate Claude Code configuration\n")
-		return nil
+	if err := config.writer().MkdirAll(filepath.Dir(claudeMdPath), config.dirMode()); err != nil {
+		return fmt.Errorf("failed to create directory for CLAUDE.md: %w", err)
 	}
 	
-	err := os.WriteFile(claudeMdPath, []byte(content.String()), 0644)
+	err := config.writer().WriteFile(claudeMdPath, []byte(output), config.fileMode())
 	if err != nil {
 		return fmt.Errorf("failed to write CLAUDE.md: %w", err)
 	}
 	
-	fmt.Printf("  ✓ Generated CLAUDE.md\n")
+	fmt.Fprintf(config.output(), "  ✓ Generated CLAUDE.md\n")
 	return nil
 }
 
-func (c *ClaudeCode) Import(rootPath string) (*ProjectConfig, error) {
+func (c *ClaudeCode) Import(ctx context.Context, rootPath string) (*ProjectConfig, error) {
 	config := &ProjectConfig{
 		RootPath: rootPath,
 	}
@@ -73,7 +60,7 @@ func (c *ClaudeCode) Import(rootPath string) (*ProjectConfig, error) {
 	// Read from CLAUDE.md
 	claudeMdPath := filepath.Join(rootPath, "CLAUDE.md")
 	if data, err := os.ReadFile(claudeMdPath); err == nil {
-		config.CursorRules = string(data)
+		config.CursorRules, config.MdcFiles = splitCombinedMarkdown(string(data), claudeCodeFormat)
 	}
 	
 	return config, nil
@@ -1,69 +1,59 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
 type Cline struct{}
 
+// clineFormat is the layout of .clinerules
+var clineFormat = markdownFormat{
+	GlobalHeading:  "# Global Instructions\n\n",
+	ContextHeading: "# Context-specific Instructions\n\n",
+	RuleLevel:      2,
+	GlobsLabel:     "File Patterns",
+}
+
 func (c *Cline) Name() string {
 	return "cline"
 }
 
+func (c *Cline) Capabilities() ToolConfig {
+	return ToolConfig{SupportsConditionalRules: false}
+}
+
 func (c *Cline) Build(config *ProjectConfig) error {
-	fmt.Printf("Building Cline configuration...\n")
+	fmt.Fprintf(config.output(), "Building Cline configuration...\n")
 	
 	// Cline uses .clinerules file
-	clinerrulesPath := filepath.Join(config.RootPath, ".clinerules")
+	clinerrulesPath := filepath.Join(config.outputDir(c.Name()), ".clinerules")
 	
 	// Build custom instructions
-	var instructions strings.Builder
-	
-	// Add global rules from .cursorrules
-	if config.CursorRules != "" {
-		instructions.WriteString("# Global Instructions\n\n")
-		instructions.WriteString(config.CursorRules)
-		instructions.WriteString("\n\n")
-	}
+	output := buildCombinedMarkdown(config, c.Name(), clineFormat)
 	
-	// Add MDC files content
-	if len(config.MdcFiles) > 0 {
-		instructions.WriteString("# Context-specific Instructions\n\n")
-		for _, mdcFile := range config.MdcFiles {
-			if mdcFile.Description != "" {
-				instructions.WriteString(fmt.Sprintf("## %s\n", mdcFile.Description))
-			}
-			if len(mdcFile.Globs) > 0 {
-				instructions.WriteString(fmt.Sprintf("**File Patterns:** %s\n", strings.Join(mdcFile.Globs, ", ")))
-			}
-			if mdcFile.AlwaysApply {
-				instructions.WriteString("**Always Apply:** Yes\n")
-			}
-			instructions.WriteString("\n")
-			instructions.WriteString(mdcFile.Content)
-			instructions.WriteString("\n\n")
-		}
+	if output == "" {
+		fmt.Fprintf(config.output(), "  ⚠ No rules found to generate Cline configuration\n")
+		return config.noRules(c.Name())
 	}
 	
-	if instructions.Len() == 0 {
-		fmt.Printf("  ⚠ No rules found to generate Cline configuration\n")
-		return nil
+	if err := config.writer().MkdirAll(filepath.Dir(clinerrulesPath), config.dirMode()); err != nil {
+		return fmt.Errorf("failed to create directory for .clinerules: %w", err)
 	}
 	
 	// Write .clinerules file
-	err := os.WriteFile(clinerrulesPath, []byte(instructions.String()), 0644)
+	err := config.writer().WriteFile(clinerrulesPath, []byte(output), config.fileMode())
 	if err != nil {
 		return fmt.Errorf("failed to write .clinerules: %w", err)
 	}
 	
-	fmt.Printf("  ✓ Updated .clinerules\n")
+	fmt.Fprintf(config.output(), "  ✓ Updated .clinerules\n")
 	return nil
 }
 
-func (c *Cline) Import(rootPath string) (*ProjectConfig, error) {
+func (c *Cline) Import(ctx context.Context, rootPath string) (*ProjectConfig, error) {
 	config := &ProjectConfig{
 		RootPath: rootPath,
 	}
@@ -71,7 +61,7 @@ func (c *Cline) Import(rootPath string) (*ProjectConfig, error) {
 	// Read from .clinerules
 	clinerrulesPath := filepath.Join(rootPath, ".clinerules")
 	if data, err := os.ReadFile(clinerrulesPath); err == nil {
-		config.CursorRules = string(data)
+		config.CursorRules, config.MdcFiles = splitCombinedMarkdown(string(data), clineFormat)
 	}
 	
 	return config, nil
@@ -0,0 +1,741 @@
+package tools
+
+import (
+	"cmp"
+	"fmt"
+	"html"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Styles of the rules in combined output files
+const (
+	// StyleMarkdown introduces each rule with a markdown heading
+	StyleMarkdown = "markdown"
+	// StyleXML wraps the global rules in <global_rules> and each rule in a
+	// <rule> tag, as recommended for structuring prompts
+	StyleXML = "xml"
+)
+
+// IsKnownStyle reports whether name is a supported output style
+func IsKnownStyle(name string) bool {
+	return name == StyleMarkdown || name == StyleXML
+}
+
+// markdownFormat describes how a tool lays out all rules in a single markdown file
+type markdownFormat struct {
+	// Header is written at the top of the file, before any rules
+	Header string
+	// GlobalHeading introduces the global rules section
+	GlobalHeading string
+	// ContextHeading introduces the context-specific rules section
+	ContextHeading string
+	// RuleLevel is the heading level used for each rule's description
+	RuleLevel int
+	// GlobsLabel labels the file patterns a rule applies to. It is only used
+	// by tools that can't scope rules to files natively.
+	GlobsLabel string
+	// nativeConditions is set from the tool's ToolConfig
+	nativeConditions bool
+	// sourceRoot is set to the project root when each rule is annotated with
+	// the path of its source file
+	sourceRoot string
+	// xml is set when the tool is configured with StyleXML
+	xml bool
+	// normalizeHeadings demotes headings in rule content below the headings
+	// the builder adds
+	normalizeHeadings bool
+	// prefix and suffix are set to the tool's framing text
+	prefix, suffix string
+	// files is set with --file-lists to list the files each rule applies to
+	files *projectFiles
+}
+
+// buildCombinedMarkdown combines the global rules and MDC rules into a single
+// markdown document. It returns an empty string when there are no rules.
+// With a token budget set, conditional rules are dropped, least binding first,
+// until the document fits; global and always-apply rules are always kept.
+func buildCombinedMarkdown(config *ProjectConfig, tool string, format markdownFormat) string {
+	globalRules := config.globalRules(tool)
+	if globalRules == "" && len(config.MdcFiles) == 0 {
+		return ""
+	}
+
+	format.nativeConditions = config.capabilities(tool).SupportsConditionalRules
+	format.xml = config.style(tool) == StyleXML
+	format.normalizeHeadings = config.Options.NormalizeHeadings && !format.xml
+	if config.Options.AnnotateSources {
+		format.sourceRoot = config.RootPath
+	}
+	format.prefix, format.suffix = config.framing(tool)
+	if !format.nativeConditions {
+		format.files = config.files
+	}
+
+	mdcFiles := config.combinedGlobs(config.sortRules(config.MdcFiles))
+	content := renderCombinedMarkdown(config, globalRules, mdcFiles, format)
+	maxTokens := config.Options.MaxTokens
+	for maxTokens > 0 && estimateTokens(content) > maxTokens {
+		i := config.trimmableRule(mdcFiles)
+		if i < 0 {
+			fmt.Fprintf(config.output(), "  ⚠ Output is ~%d tokens, over --max-tokens %d, but only global and always-apply rules are left\n", estimateTokens(content), maxTokens)
+			break
+		}
+		fmt.Fprintf(config.output(), "  ⚠ Trimmed rule %s to fit --max-tokens %d\n", ruleKey(mdcFiles[i].Path, config.rulesDir()), maxTokens)
+		mdcFiles = append(mdcFiles[:i:i], mdcFiles[i+1:]...)
+		content = renderCombinedMarkdown(config, globalRules, mdcFiles, format)
+	}
+	return content
+}
+
+// combinedGlobs returns the rules with their RootGlobs rewritten for the
+// RelativeGlobs option, leaving the config's rules untouched
+func (c *ProjectConfig) combinedGlobs(mdcFiles []MdcFile) []MdcFile {
+	mode := c.Options.RelativeGlobs
+	if mode == "" || mode == GlobsRoot {
+		return mdcFiles
+	}
+
+	rewritten := make([]MdcFile, len(mdcFiles))
+	for i, mdcFile := range mdcFiles {
+		switch mode {
+		case GlobsAuthored:
+			mdcFile.RootGlobs = mdcFile.Globs
+		case GlobsAbsolute:
+			root := filepath.ToSlash(c.RootPath)
+			globs := make([]string, len(mdcFile.RootGlobs))
+			for j, glob := range mdcFile.RootGlobs {
+				globs[j] = path.Join(root, glob)
+			}
+			mdcFile.RootGlobs = globs
+		}
+		rewritten[i] = mdcFile
+	}
+	return rewritten
+}
+
+// sortRules returns a copy of the rules in the order set by --sort-rules.
+// Rules that compare equal keep their load order.
+func (c *ProjectConfig) sortRules(mdcFiles []MdcFile) []MdcFile {
+	mode := c.Options.SortRules
+	if mode == SortNone {
+		return mdcFiles
+	}
+
+	sorted := slices.Clone(mdcFiles)
+	slices.SortStableFunc(sorted, func(a, b MdcFile) int {
+		switch mode {
+		case SortName:
+			return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		case SortPriority:
+			return c.comparePriority(a, b)
+		default:
+			return c.comparePaths(a, b)
+		}
+	})
+	return sorted
+}
+
+// comparePriority orders rules as --sort-rules priority does: by level, most
+// binding first, then by path
+func (c *ProjectConfig) comparePriority(a, b MdcFile) int {
+	return cmp.Or(cmp.Compare(levelRank(a.Level), levelRank(b.Level)), c.comparePaths(a, b))
+}
+
+// comparePaths orders rules by folder, shallowest first, then by rule key
+func (c *ProjectConfig) comparePaths(a, b MdcFile) int {
+	folderA, folderB := c.ruleFolder(a), c.ruleFolder(b)
+	return cmp.Or(
+		cmp.Compare(len(folderA), len(folderB)),
+		slices.Compare(folderA, folderB),
+		cmp.Compare(ruleKey(a.Path, c.rulesDir()), ruleKey(b.Path, c.rulesDir())),
+	)
+}
+
+// ruleFolder returns the path elements of the folder a rule applies to,
+// relative to the project root; none for rules of the project root
+func (c *ProjectConfig) ruleFolder(mdcFile MdcFile) []string {
+	if mdcFile.BaseDir == "" {
+		return nil
+	}
+	rel, err := filepath.Rel(c.RootPath, mdcFile.BaseDir)
+	if err != nil || rel == "." {
+		return nil
+	}
+	return strings.Split(filepath.ToSlash(rel), "/")
+}
+
+// levelRank orders rule levels from the most to the least binding
+func levelRank(level string) int {
+	switch level {
+	case LevelMust:
+		return 0
+	case LevelShould:
+		return 1
+	case LevelMay:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// trimmableRule returns the index of the rule to drop first to save tokens, or
+// -1 if there is none. Whatever the output order, that is the rule sorting
+// last by priority; among equals, the one placed last.
+func (c *ProjectConfig) trimmableRule(mdcFiles []MdcFile) int {
+	victim := -1
+	for i, mdcFile := range mdcFiles {
+		if mdcFile.Scope == ScopeGlobal || mdcFile.AlwaysApply || mdcFile.Position == PositionBefore {
+			continue
+		}
+		if victim < 0 || c.comparePriority(mdcFile, mdcFiles[victim]) >= 0 {
+			victim = i
+		}
+	}
+	return victim
+}
+
+func renderCombinedMarkdown(config *ProjectConfig, globalRules string, mdcFiles []MdcFile, format markdownFormat) string {
+	leadingMdcFiles, mdcFiles := splitByPosition(mdcFiles)
+	globalMdcFiles, contextMdcFiles := splitByScope(mdcFiles)
+	if config.Options.MergeGlobs && !format.nativeConditions {
+		contextMdcFiles = mergeRulesByGlobs(contextMdcFiles, format)
+	}
+
+	globalStart, globalEnd, contextHeading := format.GlobalHeading, "", format.ContextHeading
+	writeRule := writeMarkdownRule
+	if format.xml {
+		globalStart, globalEnd, contextHeading = "<global_rules>\n", "</global_rules>\n\n", ""
+		writeRule = writeXMLRule
+	}
+
+	var content strings.Builder
+	content.WriteString(format.Header)
+
+	// Add rules positioned before the global rules, then the global rules from
+	// .cursorrules, followed by rules scoped as global
+	if len(leadingMdcFiles) > 0 || globalRules != "" || len(globalMdcFiles) > 0 {
+		content.WriteString(globalStart)
+		for _, mdcFile := range leadingMdcFiles {
+			writeRule(&content, mdcFile, format)
+		}
+		if globalRules != "" {
+			if format.normalizeHeadings {
+				globalRules = normalizeHeadings(globalRules, format.RuleLevel)
+			}
+			// One blank line follows, however the rules end, so that
+			// importing the output and building again gives the same file
+			content.WriteString(strings.TrimRight(globalRules, "\n"))
+			content.WriteString("\n\n")
+		}
+		for _, mdcFile := range globalMdcFiles {
+			writeRule(&content, mdcFile, format)
+		}
+		content.WriteString(globalEnd)
+	}
+
+	// Add MDC files content
+	if len(contextMdcFiles) > 0 {
+		content.WriteString(contextHeading)
+		for _, mdcFile := range contextMdcFiles {
+			writeRule(&content, mdcFile, format)
+		}
+	}
+
+	result := content.String()
+	if config.Options.TOC && !format.xml {
+		ruleHeadings := []string{}
+		for _, mdcFiles := range [][]MdcFile{leadingMdcFiles, globalMdcFiles, contextMdcFiles} {
+			for _, mdcFile := range mdcFiles {
+				if title := ruleTitle(mdcFile); title != "" {
+					ruleHeadings = append(ruleHeadings, fmt.Sprintf("%s %s", strings.Repeat("#", format.RuleLevel), title))
+				}
+			}
+		}
+		result = insertTableOfContents(result, format, ruleHeadings)
+	}
+	if config.Options.DedupeContent {
+		result = dedupeParagraphs(result)
+	}
+	return format.frame(result)
+}
+
+// frame adds the prefix and suffix around the content of a combined output
+func (f markdownFormat) frame(content string) string {
+	if f.prefix != "" {
+		content = strings.TrimRight(f.prefix, "\n") + "\n\n" + content
+	}
+	if f.suffix != "" {
+		content = strings.TrimRight(content, "\n") + "\n\n" + strings.TrimRight(f.suffix, "\n") + "\n"
+	}
+	return content
+}
+
+// mergeRulesByGlobs combines the rules that apply to the same set of files into
+// one rule, so the file patterns are listed once. The merged rule takes the
+// place of the first of them, and each rule's content follows under a heading
+// with its description. Duplicate globs within a rule are dropped too.
+func mergeRulesByGlobs(mdcFiles []MdcFile, format markdownFormat) []MdcFile {
+	groups := [][]MdcFile{}
+	index := map[string]int{}
+	for _, mdcFile := range mdcFiles {
+		mdcFile.RootGlobs = uniqueGlobs(mdcFile.RootGlobs)
+		if len(mdcFile.RootGlobs) == 0 {
+			groups = append(groups, []MdcFile{mdcFile})
+			continue
+		}
+		sorted := append([]string{}, mdcFile.RootGlobs...)
+		sort.Strings(sorted)
+		key := fmt.Sprintf("%t\x00%s\x00%s", mdcFile.AlwaysApply, mdcFile.Level, strings.Join(sorted, "\x00"))
+		if i, ok := index[key]; ok {
+			groups[i] = append(groups[i], mdcFile)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []MdcFile{mdcFile})
+	}
+
+	merged := make([]MdcFile, 0, len(groups))
+	for _, group := range groups {
+		if len(group) == 1 {
+			merged = append(merged, group[0])
+			continue
+		}
+
+		rule := group[0]
+		names, descriptions := []string{}, []string{}
+		var content strings.Builder
+		rule.Examples, rule.References = nil, nil
+		for _, mdcFile := range group {
+			names = append(names, mdcFile.Name)
+			ruleContent := mdcFile.Content
+			if format.normalizeHeadings {
+				ruleContent = normalizeHeadings(ruleContent, format.RuleLevel+2)
+			}
+			if mdcFile.Description != "" {
+				descriptions = append(descriptions, mdcFile.Description)
+				content.WriteString(fmt.Sprintf("%s %s\n\n", strings.Repeat("#", format.RuleLevel+1), ruleTitle(mdcFile)))
+			}
+			content.WriteString(strings.TrimSpace(ruleContent))
+			content.WriteString("\n\n")
+			rule.Examples = append(rule.Examples, mdcFile.Examples...)
+			rule.References = append(rule.References, mdcFile.References...)
+		}
+		rule.Name = strings.Join(names, ", ")
+		rule.Description = strings.Join(descriptions, " / ")
+		rule.Content = strings.TrimSpace(content.String())
+		merged = append(merged, rule)
+	}
+	return merged
+}
+
+// uniqueGlobs returns globs without repeated entries, keeping their order
+func uniqueGlobs(globs []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, glob := range globs {
+		if !seen[glob] {
+			seen[glob] = true
+			unique = append(unique, glob)
+		}
+	}
+	return unique
+}
+
+// insertTableOfContents adds a list of links to the section headings and the
+// given rule headings, in document order, after the header of a combined
+// document. Anchors follow GitHub's rules, so every heading in the document is
+// counted to number repeated slugs the same way.
+func insertTableOfContents(content string, format markdownFormat, ruleHeadings []string) string {
+	sectionHeadings := map[string]bool{
+		strings.TrimSpace(format.GlobalHeading):  true,
+		strings.TrimSpace(format.ContextHeading): true,
+	}
+
+	var toc strings.Builder
+	slugs := map[string]int{}
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+		}
+		if inFence || !strings.HasPrefix(line, "#") {
+			continue
+		}
+		text := strings.TrimSpace(strings.TrimLeft(line, "#"))
+		slug := headingSlug(text)
+		if n := slugs[slug]; n > 0 {
+			slugs[slug]++
+			slug = fmt.Sprintf("%s-%d", slug, n)
+		} else {
+			slugs[slug] = 1
+		}
+
+		switch {
+		case sectionHeadings[line]:
+			toc.WriteString(fmt.Sprintf("- [%s](#%s)\n", text, slug))
+		case len(ruleHeadings) > 0 && line == ruleHeadings[0]:
+			// Headings inside the global rules content aren't listed
+			toc.WriteString(fmt.Sprintf("  - [%s](#%s)\n", text, slug))
+			ruleHeadings = ruleHeadings[1:]
+		}
+	}
+	if toc.Len() == 0 {
+		return content
+	}
+	return format.Header + "**Contents**\n\n" + toc.String() + "\n" + strings.TrimPrefix(content, format.Header)
+}
+
+// stripTableOfContents removes the list added by insertTableOfContents
+func stripTableOfContents(content string) string {
+	const title = "**Contents**\n\n"
+	if !strings.HasPrefix(content, title) {
+		return content
+	}
+	end := strings.Index(content[len(title):], "\n\n")
+	if end < 0 {
+		return content
+	}
+	return content[len(title)+end+2:]
+}
+
+// headingSlug returns the anchor GitHub generates for a heading: lowercase,
+// without punctuation, with spaces replaced by hyphens
+func headingSlug(text string) string {
+	var slug strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			slug.WriteRune(r)
+		case r == ' ':
+			slug.WriteRune('-')
+		}
+	}
+	return slug.String()
+}
+
+// notedGlobs returns globs, a rule's Globs or a rewritten copy of them, with
+// the note of each glob that has one following it in parentheses
+func notedGlobs(globs, notes []string) []string {
+	if len(notes) != len(globs) {
+		return globs
+	}
+	noted := make([]string, len(globs))
+	for i, glob := range globs {
+		noted[i] = glob
+		if notes[i] != "" {
+			noted[i] = fmt.Sprintf("%s (%s)", glob, notes[i])
+		}
+	}
+	return noted
+}
+
+// splitGlobNote reverses notedGlobs for a single glob
+func splitGlobNote(value string) (glob, note string) {
+	if i := strings.LastIndex(value, " ("); i > 0 && strings.HasSuffix(value, ")") {
+		return value[:i], value[i+2 : len(value)-1]
+	}
+	return value, ""
+}
+
+func writeMarkdownRule(content *strings.Builder, mdcFile MdcFile, format markdownFormat) {
+	if format.sourceRoot != "" {
+		content.WriteString(sourceComment(format.sourceRoot, mdcFile.Path))
+	}
+	if title := ruleTitle(mdcFile); title != "" {
+		content.WriteString(fmt.Sprintf("%s %s\n", strings.Repeat("#", format.RuleLevel), title))
+	}
+	if len(mdcFile.RootGlobs) > 0 && !format.nativeConditions {
+		content.WriteString(fmt.Sprintf("**%s:** %s\n", format.GlobsLabel, strings.Join(notedGlobs(mdcFile.RootGlobs, mdcFile.GlobNotes), ", ")))
+		if files := format.ruleFiles(mdcFile); len(files) > 0 {
+			content.WriteString(fmt.Sprintf("**Files:** %s\n", fileList(files, "`")))
+		}
+	}
+	if mdcFile.AlwaysApply {
+		content.WriteString("**Always Apply:** Yes\n")
+	}
+	content.WriteString("\n")
+	ruleContent := mdcFile.Content
+	if format.normalizeHeadings {
+		ruleContent = normalizeHeadings(ruleContent, format.RuleLevel+1)
+	}
+	// Blank lines around the content are left out, as importing drops them
+	content.WriteString(strings.Trim(ruleContent, "\n"))
+	content.WriteString("\n\n")
+	writeRuleSections(content, mdcFile, format.RuleLevel+1)
+}
+
+// ruleFiles returns the files matched by the globs of a rule with
+// --file-lists, or nil without it. The globs are made relative to the project
+// root again, as --relative-globs may have rewritten RootGlobs.
+func (f markdownFormat) ruleFiles(mdcFile MdcFile) []string {
+	if f.files == nil {
+		return nil
+	}
+	files, _ := f.files.matching(rootRelativeGlobs(f.files.root, mdcFile.BaseDir, mdcFile.Globs))
+	return files
+}
+
+// writeXMLRule writes a rule wrapped in a <rule> tag, with its metadata as
+// attributes
+func writeXMLRule(content *strings.Builder, mdcFile MdcFile, format markdownFormat) {
+	if format.sourceRoot != "" {
+		content.WriteString(sourceComment(format.sourceRoot, mdcFile.Path))
+	}
+	content.WriteString(fmt.Sprintf("<rule name=\"%s\"", html.EscapeString(mdcFile.Name)))
+	if mdcFile.Description != "" {
+		content.WriteString(fmt.Sprintf(" description=\"%s\"", html.EscapeString(mdcFile.Description)))
+	}
+	if len(mdcFile.RootGlobs) > 0 && !format.nativeConditions {
+		content.WriteString(fmt.Sprintf(" globs=\"%s\"", html.EscapeString(strings.Join(notedGlobs(mdcFile.RootGlobs, mdcFile.GlobNotes), ", "))))
+		if files := format.ruleFiles(mdcFile); len(files) > 0 {
+			content.WriteString(fmt.Sprintf(" files=\"%s\"", html.EscapeString(fileList(files, ""))))
+		}
+	}
+	if mdcFile.Level != "" {
+		content.WriteString(fmt.Sprintf(" level=\"%s\"", mdcFile.Level))
+	}
+	if mdcFile.AlwaysApply {
+		content.WriteString(" alwaysApply=\"true\"")
+	}
+	content.WriteString(">\n")
+	content.WriteString(strings.TrimSpace(mdcFile.Content))
+	content.WriteString("\n")
+	if len(mdcFile.Examples) > 0 || len(mdcFile.References) > 0 {
+		content.WriteString("\n")
+		writeRuleSections(content, mdcFile, format.RuleLevel+1)
+	}
+	content.WriteString("</rule>\n\n")
+}
+
+// normalizeHeadings demotes the headings of content so that the highest one is
+// at minLevel, keeping their relative levels and capping them at 6. Lines in
+// code fences are left alone.
+func normalizeHeadings(content string, minLevel int) string {
+	lines := strings.Split(content, "\n")
+	levels := make([]int, len(lines))
+	highest := 0
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+		}
+		if inFence {
+			continue
+		}
+		level := len(line) - len(strings.TrimLeft(line, "#"))
+		if level == 0 || level > 6 || (len(line) > level && line[level] != ' ') {
+			continue
+		}
+		levels[i] = level
+		if highest == 0 || level < highest {
+			highest = level
+		}
+	}
+	if highest == 0 || highest >= minLevel {
+		return content
+	}
+
+	shift := minLevel - highest
+	for i, level := range levels {
+		if level > 0 {
+			lines[i] = strings.Repeat("#", min(level+shift, 6)) + lines[i][level:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sourceComment returns an HTML comment naming the file a rule came from
+func sourceComment(rootPath, path string) string {
+	if rel, err := filepath.Rel(rootPath, path); err == nil {
+		path = rel
+	}
+	return fmt.Sprintf("<!-- from %s -->\n", filepath.ToSlash(path))
+}
+
+// writeRuleSections writes the examples and references of a rule, each under
+// its own heading at the given level
+func writeRuleSections(content *strings.Builder, mdcFile MdcFile, level int) {
+	sections := []struct {
+		heading string
+		items   []string
+	}{
+		{"Examples", mdcFile.Examples},
+		{"References", mdcFile.References},
+	}
+	for _, section := range sections {
+		if len(section.items) == 0 {
+			continue
+		}
+		content.WriteString(fmt.Sprintf("%s %s\n\n", strings.Repeat("#", level), section.heading))
+		for _, item := range section.items {
+			content.WriteString(fmt.Sprintf("- %s\n", item))
+		}
+		content.WriteString("\n")
+	}
+}
+
+// splitByPosition separates the rules positioned before the global rules from
+// the rest, preserving their order
+func splitByPosition(mdcFiles []MdcFile) (before []MdcFile, rest []MdcFile) {
+	for _, mdcFile := range mdcFiles {
+		if mdcFile.Position == PositionBefore {
+			before = append(before, mdcFile)
+		} else {
+			rest = append(rest, mdcFile)
+		}
+	}
+	return before, rest
+}
+
+// splitByScope separates the rules explicitly scoped as global from the rest,
+// preserving their order
+func splitByScope(mdcFiles []MdcFile) (global []MdcFile, context []MdcFile) {
+	for _, mdcFile := range mdcFiles {
+		if mdcFile.Scope == ScopeGlobal {
+			global = append(global, mdcFile)
+		} else {
+			context = append(context, mdcFile)
+		}
+	}
+	return global, context
+}
+
+// splitCombinedMarkdown reverses buildCombinedMarkdown, recovering the global
+// rules and the context-specific rules with their description, globs and
+// always-apply flag. A rule starts at a heading of the rule level that is
+// followed by its globs or always-apply line, or preceded by a source comment,
+// so headings inside rule content are not mistaken for rules. The banner,
+// header, table of contents and section headings added by the build are
+// removed, so that importing and rebuilding a file doesn't make it grow.
+func splitCombinedMarkdown(content string, format markdownFormat) (string, []MdcFile) {
+	content = strings.TrimPrefix(stripBanner(content), format.Header)
+	content = stripTableOfContents(content)
+
+	contextStart := -1
+	if format.ContextHeading != "" {
+		contextStart = strings.Index(content, format.ContextHeading)
+	}
+	if contextStart < 0 {
+		if format.GlobalHeading != "" && strings.HasPrefix(content, format.GlobalHeading) {
+			return strings.TrimSpace(strings.TrimPrefix(content, format.GlobalHeading)), nil
+		}
+		return content, nil
+	}
+
+	global := strings.TrimSpace(strings.TrimPrefix(content[:contextStart], format.GlobalHeading))
+
+	headingPrefix := strings.Repeat("#", format.RuleLevel) + " "
+	globsPrefix := "**" + format.GlobsLabel + ":** "
+	alwaysApplyLine := "**Always Apply:** Yes"
+
+	lines := strings.Split(content[contextStart+len(format.ContextHeading):], "\n")
+	mdcFiles := []MdcFile{}
+	var current *MdcFile
+	body := []string{}
+	flush := func() {
+		if current != nil {
+			current.Content = strings.TrimSpace(strings.Join(body, "\n")) + "\n"
+			mdcFiles = append(mdcFiles, *current)
+		}
+		body = []string{}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "<!-- from ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], headingPrefix) {
+			// Source comment written by --annotate-sources
+			continue
+		}
+		next := ""
+		if i+1 < len(lines) {
+			next = lines[i+1]
+		}
+		previous := ""
+		if i > 0 {
+			previous = lines[i-1]
+		}
+		isRule := strings.HasPrefix(line, headingPrefix) &&
+			(strings.HasPrefix(next, globsPrefix) || next == alwaysApplyLine || strings.HasPrefix(previous, "<!-- from "))
+		if !isRule {
+			body = append(body, line)
+			continue
+		}
+
+		flush()
+		level, description := parseRuleTitle(strings.TrimSpace(strings.TrimPrefix(line, headingPrefix)))
+		current = &MdcFile{
+			Name:        ruleNameFromDescription(description),
+			Description: description,
+			Level:       level,
+		}
+		for i+1 < len(lines) {
+			if globs, ok := strings.CutPrefix(lines[i+1], globsPrefix); ok {
+				current.Globs = splitGlobList(globs)
+				notes := make([]string, len(current.Globs))
+				noted := false
+				for j := range current.Globs {
+					current.Globs[j], notes[j] = splitGlobNote(strings.TrimSpace(current.Globs[j]))
+					noted = noted || notes[j] != ""
+				}
+				if noted {
+					current.GlobNotes = notes
+				}
+				current.RootGlobs = current.Globs
+			} else if lines[i+1] == alwaysApplyLine {
+				current.AlwaysApply = true
+			} else {
+				break
+			}
+			i++
+		}
+	}
+	flush()
+
+	return global, mdcFiles
+}
+
+// ruleTitle returns the heading of a rule in generated files: its description,
+// preceded by its level in capitals when it has one, e.g. "[MUST] Testing"
+func ruleTitle(mdcFile MdcFile) string {
+	if mdcFile.Level == "" {
+		return mdcFile.Description
+	}
+	return strings.TrimSpace(fmt.Sprintf("[%s] %s", strings.ToUpper(mdcFile.Level), mdcFile.Description))
+}
+
+// parseRuleTitle splits a heading written by ruleTitle into the rule's level
+// and description
+func parseRuleTitle(title string) (string, string) {
+	for _, level := range []string{LevelMust, LevelShould, LevelMay} {
+		prefix := "[" + strings.ToUpper(level) + "]"
+		if title == prefix {
+			return level, ""
+		}
+		if description, ok := strings.CutPrefix(title, prefix+" "); ok {
+			return level, description
+		}
+	}
+	return "", title
+}
+
+// ruleNameFromDescription derives a file-name friendly rule name from a
+// description, such as react-component-rules for "React Component Rules"
+func ruleNameFromDescription(description string) string {
+	var name strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(description) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			name.WriteRune(r)
+			dash = false
+		} else if !dash && name.Len() > 0 {
+			name.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(name.String(), "-")
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// commitMessage is used for the commits of generated files
+const commitMessage = "Update AI tool configurations generated by syncai"
+
+// commitOutputs commits the files generated by tools in the output directory,
+// which must be inside a git work tree. Nothing is committed when the files are
+// unchanged.
+func commitOutputs(config *ProjectConfig, tools []AITool) error {
+	dir := config.outputRoot()
+
+	paths := []string{}
+	for _, tool := range tools {
+		memory, err := buildInMemory(config, tool)
+		if err != nil {
+			return fmt.Errorf("failed to determine outputs of %s: %w", tool.Name(), err)
+		}
+		for _, path := range memory.Paths() {
+			if _, err := os.Stat(path); err == nil {
+				paths = append(paths, path)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	// Generated files are often ignored on the source branch, so force adding them
+	if err := runGit(dir, append([]string{"add", "--force", "--"}, paths...)...); err != nil {
+		return err
+	}
+	// git diff --cached --quiet exits with 1 when there are staged changes
+	if err := exec.Command("git", "-C", dir, "diff", "--cached", "--quiet").Run(); err == nil {
+		fmt.Fprintf(config.output(), "  ✓ Generated files in %s are already committed\n", dir)
+		return nil
+	}
+	if err := runGit(dir, "commit", "--quiet", "-m", commitMessage); err != nil {
+		return err
+	}
+	fmt.Fprintf(config.output(), "  ✓ Committed generated files in %s\n", dir)
+	return nil
+}
+
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// estimateTokens roughly estimates the number of LLM tokens in content,
+// assuming about four characters per token
+func estimateTokens(content string) int {
+	return (utf8.RuneCountInString(content) + 3) / 4
+}
+
+// splitParagraphs splits markdown into blocks separated by blank lines.
+// Fenced code blocks are kept whole even if they contain blank lines.
+func splitParagraphs(content string) []string {
+	paragraphs := []string{}
+	current := []string{}
+	inFence := false
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if trimmed == "" && !inFence {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, strings.Join(current, "\n"))
+				current = []string{}
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, strings.Join(current, "\n"))
+	}
+
+	return paragraphs
+}
+
+// xmlTagLinePattern matches a line holding only an opening or closing tag, as
+// written around rules in XML style
+var xmlTagLinePattern = regexp.MustCompile(`^\s*</?[A-Za-z][\w-]*(\s[^<>]*)?>\s*$`)
+
+// dedupeParagraphs removes paragraphs that repeat an earlier one, ignoring
+// differences in whitespace. Headings are kept as they structure the output,
+// and so are tag lines at the start or end of a paragraph, so dropping a
+// repeated rule body never drops the tags around it.
+func dedupeParagraphs(content string) string {
+	seen := map[[sha256.Size]byte]bool{}
+	kept := []string{}
+
+	for _, paragraph := range splitParagraphs(content) {
+		if strings.HasPrefix(strings.TrimSpace(paragraph), "#") && !strings.Contains(paragraph, "\n") {
+			kept = append(kept, paragraph)
+			continue
+		}
+
+		lines := strings.Split(paragraph, "\n")
+		start, end := 0, len(lines)
+		for start < end && xmlTagLinePattern.MatchString(lines[start]) {
+			start++
+		}
+		for end > start && xmlTagLinePattern.MatchString(lines[end-1]) {
+			end--
+		}
+		if start == end {
+			kept = append(kept, paragraph)
+			continue
+		}
+
+		key := sha256.Sum256([]byte(strings.Join(strings.Fields(strings.Join(lines[start:end], "\n")), " ")))
+		if seen[key] {
+			if tags := append(lines[:start:start], lines[end:]...); len(tags) > 0 {
+				kept = append(kept, strings.Join(tags, "\n"))
+			}
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, paragraph)
+	}
+
+	return strings.Join(kept, "\n\n") + "\n"
+}
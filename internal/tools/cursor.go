@@ -1,54 +1,117 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
 type Cursor struct{}
 
+// cursorLegacyFormat is the layout of the .cursorrules file written for
+// versions of Cursor without .cursor/rules
+var cursorLegacyFormat = markdownFormat{
+	GlobalHeading:  "# Global Rules\n\n",
+	ContextHeading: "# Context-specific Rules\n\n",
+	RuleLevel:      2,
+	GlobsLabel:     "Applies to",
+}
+
 func (c *Cursor) Name() string {
 	return "cursor"
 }
 
+func (c *Cursor) Capabilities() ToolConfig {
+	return ToolConfig{
+		SupportsConditionalRules: true,
+		SupportsFolderRules:      true,
+		DefaultVersion:           CursorModern,
+		Versions: map[string]ToolConfig{
+			CursorLegacy: {SupportsConditionalRules: false},
+		},
+	}
+}
+
 func (c *Cursor) Build(config *ProjectConfig) error {
-	fmt.Printf("Building Cursor configuration...\n")
+	fmt.Fprintf(config.output(), "Building Cursor configuration...\n")
+	
+	if config.toolVersion(c.Name()) == CursorLegacy {
+		return c.buildLegacy(config)
+	}
 	
 	// Cursor already uses .cursorrules and .cursor/rules/*.mdc files
 	// So we don't need to generate anything - just validate
+	if config.rulesDir() != DefaultRulesDir && len(config.MdcFiles) > 0 {
+		fmt.Fprintf(config.output(), "  ⚠ Rules are read from %s, which Cursor doesn't load; only .cursorrules applies to Cursor\n", config.rulesDir())
+	}
 	
 	if config.CursorRules != "" {
-		fmt.Printf("  ✓ .cursorrules file found\n")
+		fmt.Fprintf(config.output(), "  ✓ .cursorrules file found\n")
 	}
 	
 	if len(config.MdcFiles) > 0 {
-		fmt.Printf("  ✓ %d MDC rule files found\n", len(config.MdcFiles))
+		fmt.Fprintf(config.output(), "  ✓ %d MDC rule files found\n", len(config.MdcFiles))
+	}
+	
+	if config.CursorRules == "" && len(config.MdcFiles) == 0 {
+		return config.noRules(c.Name())
 	}
 	
 	return nil
 }
 
-func (c *Cursor) Import(rootPath string) (*ProjectConfig, error) {
+// buildLegacy combines every rule into a single .cursorrules file. It is never
+// written over the project's own .cursorrules, which is a source of the build.
+func (c *Cursor) buildLegacy(config *ProjectConfig) error {
+	cursorRulesPath := filepath.Join(config.outputDir(c.Name()), ".cursorrules")
+	if config.isCursorRulesPath(cursorRulesPath) {
+		return fmt.Errorf("legacy Cursor output would overwrite the source .cursorrules, write it elsewhere with --tool-dir cursor=<dir> or --output-dir")
+	}
+
+	output := buildCombinedMarkdown(config, c.Name(), cursorLegacyFormat)
+	if output == "" {
+		fmt.Fprintf(config.output(), "  ⚠ No rules found to generate .cursorrules\n")
+		return config.noRules(c.Name())
+	}
+
+	if err := config.writer().MkdirAll(filepath.Dir(cursorRulesPath), config.dirMode()); err != nil {
+		return fmt.Errorf("failed to create directory for .cursorrules: %w", err)
+	}
+	if err := config.writer().WriteFile(cursorRulesPath, []byte(output), config.fileMode()); err != nil {
+		return fmt.Errorf("failed to write .cursorrules: %w", err)
+	}
+
+	fmt.Fprintf(config.output(), "  ✓ Generated .cursorrules (legacy format)\n")
+	return nil
+}
+
+func (c *Cursor) Import(ctx context.Context, rootPath string) (*ProjectConfig, error) {
 	// For Cursor, we just read the existing files
 	config := &ProjectConfig{
 		RootPath: rootPath,
 	}
 	
 	// Load .cursorrules file
-	cursorRulesPath := filepath.Join(rootPath, ".cursorrules")
-	if data, err := os.ReadFile(cursorRulesPath); err == nil {
-		config.CursorRules = string(data)
+	config.CursorRules = readCursorRules(rootPath)
+
+	// Rule files use the extensions configured for the project
+	settings, err := loadSettings(rootPath, "")
+	if err != nil {
+		return nil, err
 	}
+	extensions := settings.ruleExtensions()
 	
 	// Find .cursor directories and load MDC files
-	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if err != nil {
-			return err
+			return skipUnreadable(&config.Warnings, path, info, err)
 		}
-		if info.IsDir() && info.Name() == ".cursor" {
-			config.CursorDirs = append(config.CursorDirs, path)
+		if info.IsDir() && isRulesDir(path, DefaultRulesDir) {
+			config.RulesDirs = append(config.RulesDirs, path)
 		}
 		return nil
 	})
@@ -58,21 +121,26 @@ func (c *Cursor) Import(rootPath string) (*ProjectConfig, error) {
 	}
 	
 	// Load MDC files
-	for _, cursorDir := range config.CursorDirs {
-		rulesDir := filepath.Join(cursorDir, "rules")
-		if _, err := os.Stat(rulesDir); os.IsNotExist(err) {
-			continue
-		}
-		
+	sortRulesDirs(config.RulesDirs)
+	for _, rulesDir := range config.RulesDirs {
+		baseDir := rulesBaseDir(rulesDir, DefaultRulesDir)
 		err = filepath.Walk(rulesDir, func(path string, info os.FileInfo, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			if err != nil {
-				return err
+				return skipUnreadable(&config.Warnings, path, info, err)
 			}
-			if !info.IsDir() && strings.HasSuffix(path, ".mdc") {
-				mdcFile, err := parseMdcFile(path)
+			if !info.IsDir() && hasRuleExtension(path, extensions) {
+				mdcFile, warnings, err := parseMdcFile(rootPath, path)
 				if err != nil {
-					return err
+					// Import the other rules, as the build does
+					config.Warnings = append(config.Warnings, Warning{Path: path, Message: fmt.Sprintf("skipped rule that failed to parse: %v", err), Severity: SeverityError})
+					return nil
 				}
+				config.Warnings = append(config.Warnings, warnings...)
+				mdcFile.RootGlobs = rootRelativeGlobs(rootPath, baseDir, mdcFile.Globs)
+				mdcFile.BaseDir = baseDir
 				config.MdcFiles = append(config.MdcFiles, *mdcFile)
 			}
 			return nil
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCursorImport(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".syncai.yaml":             "ruleExtensions: [.mdc, .md]\n",
+		".cursorrules":             "Use tabs.\n",
+		".cursor/rules/react.mdc":  "---\ndescription: React\nglobs: \"*.tsx\"\n---\nUse hooks.\n",
+		".cursor/rules/style.md":   "---\ndescription: Style\nalwaysApply: true\n---\nKeep lines short.\n",
+		".cursor/rules/notes.txt":  "Not a rule.\n",
+		".cursor/rules/broken.mdc": "---\ndescription: Broken\ncontentFrom: missing.md\n---\n",
+	})
+
+	config, err := (&Cursor{}).Import(context.Background(), root)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if config.CursorRules != "Use tabs.\n" {
+		t.Errorf("got .cursorrules %q", config.CursorRules)
+	}
+	names := []string{}
+	for _, mdcFile := range config.MdcFiles {
+		names = append(names, mdcFile.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"react", "style"}) {
+		t.Errorf("imported rules %v, want react and style", names)
+	}
+
+	broken := filepath.Join(root, ".cursor", "rules", "broken.mdc")
+	found := false
+	for _, warning := range config.Warnings {
+		found = found || (warning.Path == broken && warning.Severity == SeverityError)
+	}
+	if !found {
+		t.Errorf("no error warning for %s in %v", broken, config.Warnings)
+	}
+}
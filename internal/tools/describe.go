@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Describe prints where a single rule ends up for each AI tool: the generated
+// files it contributes to, and whether it gets a file of its own, is inlined
+// into a combined file or is dropped. Each tool is built in memory with and
+// without the rule, and the outputs are compared, so no files are written.
+func Describe(name string, targets []string, opts BuildOptions) error {
+	opts.readOnly = true
+	config, err := loadBuildConfig(opts)
+	if err != nil {
+		return err
+	}
+	reportWarnings(os.Stderr, config.Warnings)
+
+	var rule *MdcFile
+	without := []MdcFile{}
+	for i, mdcFile := range config.MdcFiles {
+		if mdcFile.Name != name {
+			without = append(without, mdcFile)
+			continue
+		}
+		if rule != nil {
+			return fmt.Errorf("rule name %s is ambiguous: %s and %s", name, rule.Path, mdcFile.Path)
+		}
+		rule = &config.MdcFiles[i]
+	}
+	if rule == nil {
+		return fmt.Errorf("rule not found: %s", name)
+	}
+
+	tools, err := resolveTools(config, targets)
+	if err != nil {
+		return err
+	}
+
+	withoutConfig := *config
+	withoutConfig.MdcFiles = without
+
+	relPath, _ := filepath.Rel(config.RootPath, rule.Path)
+	fmt.Printf("Rule %s (%s):\n", name, filepath.ToSlash(relPath))
+	for _, tool := range tools {
+		routes, err := describeRule(config, &withoutConfig, tool, *rule)
+		if err != nil {
+			return fmt.Errorf("failed to build %s: %w", tool.Name(), err)
+		}
+		for i, route := range routes {
+			toolName := tool.Name()
+			if i > 0 {
+				toolName = ""
+			}
+			fmt.Printf("  %-12s %s\n", toolName, route)
+		}
+	}
+	return nil
+}
+
+// describeRule returns one line for each way a tool's output contains the
+// rule, comparing a build with the rule against one without it
+func describeRule(config, withoutConfig *ProjectConfig, tool AITool, rule MdcFile) ([]string, error) {
+	if tool.Name() == "cursor" && config.toolVersion(tool.Name()) != CursorLegacy {
+		relPath, _ := filepath.Rel(config.RootPath, rule.Path)
+		return []string{fmt.Sprintf("read natively from %s", filepath.ToSlash(relPath))}, nil
+	}
+
+	with, err := buildInMemory(config, tool)
+	if err != nil {
+		return nil, err
+	}
+	withoutRule, err := buildInMemory(withoutConfig, tool)
+	if err != nil {
+		return nil, err
+	}
+
+	capabilities := config.capabilities(tool.Name())
+	notes := []string{}
+	if len(rule.Globs) > 0 && !capabilities.SupportsConditionalRules {
+		notes = append(notes, "globs inlined as text")
+	}
+
+	routes := []string{}
+	for _, path := range with.Paths() {
+		data, _ := with.ReadFile(path)
+		previous, existed := withoutRule.ReadFile(path)
+		relPath, _ := filepath.Rel(config.outputRoot(), path)
+		relPath = filepath.ToSlash(relPath)
+		switch {
+		case !existed:
+			routes = append(routes, fmt.Sprintf("separate file %s", relPath))
+		case !bytes.Equal(data, previous):
+			section := "context-specific rules"
+			if rule.Scope == ScopeGlobal || rule.Position == PositionBefore {
+				section = "global rules"
+			}
+			routes = append(routes, fmt.Sprintf("inlined into %s (%s)", relPath, strings.Join(append([]string{section}, notes...), ", ")))
+		}
+	}
+	if len(routes) == 0 {
+		routes = append(routes, "dropped")
+	}
+	return routes, nil
+}
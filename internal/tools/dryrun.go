@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Actions of the operations planned by a dry run
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+	ActionDelete    = "delete"
+)
+
+// plannedOperation is an entry of the JSON written by --dry-run --json. Tool
+// is empty for files not generated by a tool, such as .gitattributes.
+type plannedOperation struct {
+	Tool   string `json:"tool"`
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Bytes  int    `json:"bytes"`
+}
+
+// dryRunAction returns whether writing data to path would create, update or
+// leave the file unchanged
+func dryRunAction(path string, data []byte) string {
+	existing, err := os.ReadFile(path)
+	switch {
+	case err != nil:
+		return ActionCreate
+	case string(existing) == string(data):
+		return ActionUnchanged
+	default:
+		return ActionUpdate
+	}
+}
+
+// writeDryRunPlan writes the operations of a dry run to w as a JSON array,
+// sorted by tool and path. The files each tool would write are taken from the
+// build report; removals are the operations planned by --prune.
+func writeDryRunPlan(w io.Writer, config *ProjectConfig, memory *MemoryFileWriter, removals []plannedOperation) error {
+	tools := map[string]string{}
+	for tool, files := range config.report.files {
+		for _, file := range files {
+			tools[file.Path] = tool
+		}
+	}
+
+	plan := []plannedOperation{}
+	for _, path := range memory.Paths() {
+		data, _ := memory.ReadFile(path)
+		plan = append(plan, plannedOperation{
+			Tool:   tools[path],
+			Path:   config.planPath(path),
+			Action: dryRunAction(path, data),
+			Bytes:  len(data),
+		})
+	}
+	plan = append(plan, removals...)
+	sort.Slice(plan, func(i, j int) bool {
+		if plan[i].Tool != plan[j].Tool {
+			return plan[i].Tool < plan[j].Tool
+		}
+		return plan[i].Path < plan[j].Path
+	})
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode dry run plan: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// planPath returns path relative to the output root with forward slashes
+func (c *ProjectConfig) planPath(path string) string {
+	if rel, err := filepath.Rel(c.outputRoot(), path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// CursorIgnoreFileName is the file, at the project root, listing paths Cursor
+// doesn't index. Its files are left out of the file lists of rules.
+const CursorIgnoreFileName = ".cursorignore"
+
+// maxListedFiles is the number of files listed for a rule in a generated file
+// before the rest are summarized
+const maxListedFiles = 20
+
+// ignorePattern is a line of .cursorignore, in gitignore syntax
+type ignorePattern struct {
+	glob    string
+	negate  bool
+	dirOnly bool
+}
+
+// cursorIgnore matches paths against the patterns of .cursorignore
+type cursorIgnore struct {
+	patterns []ignorePattern
+}
+
+// loadCursorIgnore reads the .cursorignore of the project at rootPath. A
+// missing file ignores nothing.
+func loadCursorIgnore(rootPath string) (*cursorIgnore, error) {
+	return loadIgnoreFile(rootPath, CursorIgnoreFileName)
+}
+
+// loadIgnoreFile reads a file in gitignore syntax, such as .gitignore, at the
+// project root. A missing file ignores nothing.
+func loadIgnoreFile(rootPath, name string) (*cursorIgnore, error) {
+	data, err := os.ReadFile(filepath.Join(rootPath, name))
+	if os.IsNotExist(err) {
+		return &cursorIgnore{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return parseCursorIgnore(string(data)), nil
+}
+
+// parseCursorIgnore parses gitignore syntax: # comments, ! to re-include a
+// path, a trailing / to match only directories, and a leading or inner / to
+// anchor a pattern at the root. Other patterns match at any depth.
+func parseCursorIgnore(data string) *cursorIgnore {
+	ignore := &cursorIgnore{}
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pattern := ignorePattern{}
+		if strings.HasPrefix(line, "!") {
+			pattern.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			pattern.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		pattern.glob = strings.TrimPrefix(line, "/")
+		if pattern.glob != "" {
+			ignore.patterns = append(ignore.patterns, pattern)
+		}
+	}
+	return ignore
+}
+
+// matches reports whether the path, relative to the project root with
+// forward slashes, is ignored. The last matching pattern wins.
+func (i *cursorIgnore) matches(rel string, isDir bool) bool {
+	ignored := false
+	for _, pattern := range i.patterns {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		if matchPathGlob(pattern.glob, rel) {
+			ignored = !pattern.negate
+		}
+	}
+	return ignored
+}
+
+// projectFiles holds the files of the project, relative to the root with
+// forward slashes, split by whether .cursorignore leaves them out
+type projectFiles struct {
+	root    string
+	files   []string
+	ignored []string
+}
+
+// loadProjectFiles lists the files of the project at rootPath. Version
+// control, the syncai cache and the directories tools write to are skipped.
+// As in git, the files of an ignored directory can't be included again.
+func loadProjectFiles(rootPath string) (*projectFiles, []Warning, error) {
+	ignore, err := loadCursorIgnore(rootPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := &projectFiles{root: rootPath}
+	warnings := []Warning{}
+	ignoredDirs := map[string]bool{}
+	err = filepath.Walk(rootPath, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return skipUnreadable(&warnings, file, info, err)
+		}
+		rel, err := filepath.Rel(rootPath, file)
+		if err != nil || rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		ignored := ignoredDirs[path.Dir(rel)]
+		if info.IsDir() {
+			if info.Name() == ".git" || info.Name() == ".syncai" || isOutputDirName(info.Name()) {
+				return filepath.SkipDir
+			}
+			ignoredDirs[rel] = ignored || ignore.matches(rel, true)
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if ignored || ignore.matches(rel, false) {
+			files.ignored = append(files.ignored, rel)
+		} else {
+			files.files = append(files.files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list project files: %w", err)
+	}
+	return files, warnings, nil
+}
+
+// matching returns the files matched by one of globs, relative to the project
+// root, and the number of matching files left out by .cursorignore
+func (p *projectFiles) matching(globs []string) (files []string, ignored int) {
+	patterns := []string{}
+	for _, glob := range globs {
+		for _, pattern := range expandBraces(glob) {
+			pattern = strings.TrimPrefix(strings.TrimPrefix(pattern, "./"), "/")
+			// Like Cursor, a glob without a directory matches at any depth
+			if !strings.Contains(pattern, "/") {
+				pattern = "**/" + pattern
+			}
+			patterns = append(patterns, pattern)
+		}
+	}
+	match := func(file string) bool {
+		for _, pattern := range patterns {
+			if matchPathGlob(pattern, file) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, file := range p.files {
+		if match(file) {
+			files = append(files, file)
+		}
+	}
+	for _, file := range p.ignored {
+		if match(file) {
+			ignored++
+		}
+	}
+	return files, ignored
+}
+
+// expandBraces expands each {a,b} group of glob into the globs it stands for,
+// as path.Match doesn't support them
+func expandBraces(glob string) []string {
+	start := strings.Index(glob, "{")
+	if start < 0 {
+		return []string{glob}
+	}
+	end := strings.Index(glob[start:], "}")
+	if end < 0 {
+		return []string{glob}
+	}
+	end += start
+
+	globs := []string{}
+	for _, alternative := range strings.Split(glob[start+1:end], ",") {
+		globs = append(globs, expandBraces(glob[:start]+alternative+glob[end+1:])...)
+	}
+	return globs
+}
+
+// fileList formats the files a rule applies to for a generated file, each
+// wrapped in quote, listing at most maxListedFiles of them
+func fileList(files []string, quote string) string {
+	quoted := []string{}
+	for i, file := range files {
+		if i == maxListedFiles {
+			quoted = append(quoted, fmt.Sprintf("and %d more", len(files)-maxListedFiles))
+			break
+		}
+		quoted = append(quoted, quote+file+quote)
+	}
+	return strings.Join(quoted, ", ")
+}
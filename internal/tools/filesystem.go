@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// FileWriter abstracts the file system operations used to write generated files
+type FileWriter interface {
+	WriteFile(path string, data []byte, perm os.FileMode) error
+	MkdirAll(path string, perm os.FileMode) error
+}
+
+// OSFileWriter writes generated files to disk
+type OSFileWriter struct {
+	// Backup saves the previous content of a file that is about to change to
+	// <path>.bak
+	Backup bool
+}
+
+// WriteFile writes data to path, leaving the file untouched when it already
+// has the same content so that repeated builds don't modify anything. Files
+// made read-only by an earlier build are made writable again to be replaced.
+func (w OSFileWriter) WriteFile(path string, data []byte, perm os.FileMode) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if err := os.WriteFile(path, data, perm); err != nil {
+			return err
+		}
+		// Apply the permissions exactly, regardless of the umask
+		return os.Chmod(path, perm)
+	}
+
+	if existing, err := os.ReadFile(path); err != nil || !bytes.Equal(existing, data) {
+		if w.Backup && err == nil {
+			if err := os.WriteFile(path+".bak", existing, 0644); err != nil {
+				return fmt.Errorf("failed to back up %s: %w", path, err)
+			}
+		}
+		if err := os.Chmod(path, info.Mode().Perm()|0200); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, perm); err != nil {
+			return err
+		}
+	}
+	if info.Mode().Perm() == perm {
+		return nil
+	}
+	return os.Chmod(path, perm)
+}
+
+// MkdirAll creates path and its parents. Each newly created directory gets
+// exactly perm, regardless of the umask; existing directories are left as they
+// are.
+func (OSFileWriter) MkdirAll(path string, perm os.FileMode) error {
+	// Missing directories, from path up to the first existing parent
+	created := []string{}
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		created = append(created, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	if len(created) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(path, perm); err != nil {
+		return err
+	}
+	// Deepest first, so that a perm without search permission doesn't keep
+	// the parents from reaching their children
+	for _, dir := range created {
+		if err := os.Chmod(dir, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MemoryFileWriter keeps generated files in memory instead of writing them to disk.
+// It is safe for concurrent use by tools building in parallel.
+type MemoryFileWriter struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+func NewMemoryFileWriter() *MemoryFileWriter {
+	return &MemoryFileWriter{
+		files: map[string][]byte{},
+		dirs:  map[string]bool{},
+	}
+}
+
+func (m *MemoryFileWriter) WriteFile(path string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files[filepath.Clean(path)] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *MemoryFileWriter) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.dirs[filepath.Clean(path)] = true
+	return nil
+}
+
+// ReadFile returns the content written to path, if any
+func (m *MemoryFileWriter) ReadFile(path string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.files[filepath.Clean(path)]
+	return data, ok
+}
+
+// Dirs returns the directories created so far, sorted
+func (m *MemoryFileWriter) Dirs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dirs := make([]string, 0, len(m.dirs))
+	for dir := range m.dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// Paths returns the paths of all files written so far, sorted
+func (m *MemoryFileWriter) Paths() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	paths := make([]string, 0, len(m.files))
+	for path := range m.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Files returns a copy of all files written so far, keyed by path
+func (m *MemoryFileWriter) Files() map[string][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	files := make(map[string][]byte, len(m.files))
+	for path, data := range m.files {
+		files[path] = data
+	}
+	return files
+}
+
+// buildInMemory builds a tool without writing to disk or printing progress,
+// returning the files it would have written
+func buildInMemory(config *ProjectConfig, tool AITool) (*MemoryFileWriter, error) {
+	memory := NewMemoryFileWriter()
+	buildConfig := *config
+	buildConfig.Writer = memory
+	buildConfig.Output = io.Discard
+
+	if err := tool.Build(buildConfig.forTool(tool.Name())); err != nil {
+		return nil, err
+	}
+	return memory, nil
+}
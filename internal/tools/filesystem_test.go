@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOSFileWriterMkdirAll(t *testing.T) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "existing")
+	if err := os.Mkdir(existing, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	// Group write permission, which the usual umask of 022 would remove
+	path := filepath.Join(existing, "a", "b", "c")
+	if err := (OSFileWriter{}).MkdirAll(path, 0775); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"a", "a/b", "a/b/c"} {
+		info, err := os.Stat(filepath.Join(existing, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0775 {
+			t.Errorf("%s has mode %v, want 0775", dir, info.Mode().Perm())
+		}
+	}
+	info, err := os.Stat(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("existing directory changed to mode %v", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Markers of the block of .gitattributes managed by --manage-gitattributes
+const (
+	gitattributesBegin = "# BEGIN syncai generated files"
+	gitattributesEnd   = "# END syncai generated files"
+)
+
+// updateGitattributes marks the files generated by tools as linguist-generated
+// in the .gitattributes of the output directory, so git hosts collapse them in
+// diffs. The entries are kept in a block between marker comments that is
+// rewritten on every build: entries of files that still exist are kept, so
+// building some tools doesn't drop the others, and the targeted tools' files
+// are added. The rest of the file is left as is, and files it already lists
+// aren't repeated.
+func updateGitattributes(config *ProjectConfig, tools []AITool) error {
+	root := config.outputRoot()
+	path := filepath.Join(root, ".gitattributes")
+
+	existing := ""
+	if data, err := os.ReadFile(path); err == nil {
+		existing = string(data)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read .gitattributes: %w", err)
+	}
+	kept, listed, previous := withoutGitattributesBlock(existing)
+
+	entries := []string{}
+	for _, entry := range previous {
+		pattern := strings.Fields(entry)[0]
+		file := filepath.Join(root, filepath.FromSlash(strings.ReplaceAll(strings.TrimPrefix(pattern, "/"), "[[:space:]]", " ")))
+		if _, err := os.Stat(file); err == nil && !listed[pattern] {
+			listed[pattern] = true
+			entries = append(entries, entry)
+		}
+	}
+	for _, tool := range tools {
+		memory, err := buildInMemory(config, tool)
+		if err != nil {
+			return fmt.Errorf("failed to determine outputs of %s: %w", tool.Name(), err)
+		}
+		for _, output := range memory.Paths() {
+			rel, err := filepath.Rel(root, output)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			pattern := "/" + strings.ReplaceAll(filepath.ToSlash(rel), " ", "[[:space:]]")
+			if !listed[pattern] {
+				listed[pattern] = true
+				entries = append(entries, pattern+" linguist-generated=true")
+			}
+		}
+	}
+
+	sort.Strings(entries)
+
+	updated := kept
+	if len(entries) > 0 {
+		if updated != "" {
+			updated += "\n"
+		}
+		updated += gitattributesBegin + "\n" + strings.Join(entries, "\n") + "\n" + gitattributesEnd + "\n"
+	}
+	if updated == existing {
+		return nil
+	}
+
+	if err := config.writer().WriteFile(path, []byte(updated), 0644); err != nil {
+		return fmt.Errorf("failed to write .gitattributes: %w", err)
+	}
+	if !config.Options.DryRun {
+		fmt.Fprintf(config.output(), "  ✓ Updated .gitattributes\n")
+	}
+	return nil
+}
+
+// withoutGitattributesBlock returns the content of a .gitattributes without
+// the block managed by syncai, the patterns listed in the rest of it, and the
+// entries of the block
+func withoutGitattributesBlock(content string) (string, map[string]bool, []string) {
+	lines := []string{}
+	listed := map[string]bool{}
+	block := []string{}
+	inBlock := false
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		switch {
+		case line == gitattributesBegin:
+			inBlock = true
+		case line == gitattributesEnd:
+			inBlock = false
+		case inBlock:
+			if strings.TrimSpace(line) != "" {
+				block = append(block, line)
+			}
+		default:
+			lines = append(lines, line)
+			if fields := strings.Fields(line); len(fields) > 0 && !strings.HasPrefix(fields[0], "#") {
+				listed[fields[0]] = true
+			}
+		}
+	}
+	kept := strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	if kept != "" {
+		kept += "\n"
+	}
+	return kept, listed, block
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// chooseImportSource picks the tool to convert to MDC rules among the found
+// tools other than Cursor. from selects it explicitly. Otherwise the user is
+// asked when several tools are found and both stdin and stdout are terminals,
+// and the first one is used when they aren't. An empty name is returned when
+// only Cursor rules were found.
+func chooseImportSource(found []string, from string) (string, error) {
+	candidates := []string{}
+	for _, name := range found {
+		if name != "cursor" {
+			candidates = append(candidates, name)
+		}
+	}
+
+	if from == "cursor" {
+		return "", fmt.Errorf("cursor rules are already MDC rules, nothing to convert")
+	}
+	if from != "" {
+		for _, name := range candidates {
+			if name == from {
+				return name, nil
+			}
+		}
+		return "", fmt.Errorf("no %s configuration found to import from", from)
+	}
+
+	if len(candidates) == 0 {
+		return "", nil
+	}
+	if len(candidates) == 1 || !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return candidates[0], nil
+	}
+	return promptImportSource(candidates, os.Stdin, os.Stdout)
+}
+
+// promptImportSource lists the candidates and reads the number of the chosen
+// one, asking again until a valid number is entered
+func promptImportSource(candidates []string, in io.Reader, out io.Writer) (string, error) {
+	fmt.Fprintf(out, "  Which configuration do you want to import from?\n")
+	for i, name := range candidates {
+		fmt.Fprintf(out, "    %d) %s\n", i+1, name)
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(out, "  Enter a number [1]: ")
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", fmt.Errorf("failed to read choice: %w", err)
+			}
+			return "", fmt.Errorf("no configuration chosen to import from")
+		}
+		answer := strings.TrimSpace(scanner.Text())
+		if answer == "" {
+			return candidates[0], nil
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(candidates) {
+			return candidates[n-1], nil
+		}
+		fmt.Fprintf(out, "  Please enter a number from 1 to %d\n", len(candidates))
+	}
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe
+// or a file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// importRulesDir returns the relative rules directory imported rules are
+// written to: the RulesDir option, else the one in the settings
+func importRulesDir(rootPath string, opts ImportOptions) (string, error) {
+	if opts.RulesDir != "" {
+		rulesDir, err := cleanRulesDir(opts.RulesDir)
+		if err != nil {
+			return "", fmt.Errorf("--rules-dir: %w", err)
+		}
+		return rulesDir, nil
+	}
+	settings, err := loadSettings(rootPath, opts.ConfigPath)
+	if err != nil {
+		return "", err
+	}
+	return settings.rulesDir(), nil
+}
+
+// importToMdc writes the global rules imported from source to imported.mdc in
+// the rules directory, and each rule recovered from its output to a file
+// named after the rule, so they round-trip into the MDC rule format
+func importToMdc(rootPath, rulesDir, source string, config *ProjectConfig) error {
+	mdcFiles := []MdcFile{}
+	if strings.TrimSpace(config.CursorRules) != "" {
+		mdcFiles = append(mdcFiles, MdcFile{
+			Name:        "imported",
+			Description: fmt.Sprintf("Rules imported from %s", source),
+			AlwaysApply: true,
+			Content:     strings.TrimSpace(config.CursorRules) + "\n",
+		})
+	}
+	mdcFiles = append(mdcFiles, config.MdcFiles...)
+
+	dir := filepath.Join(rootPath, filepath.FromSlash(rulesDir))
+	for _, mdcFile := range mdcFiles {
+		if _, err := os.Stat(filepath.Join(dir, mdcFile.Name+".mdc")); err == nil {
+			return fmt.Errorf("%s already exists", filepath.Join(dir, mdcFile.Name+".mdc"))
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", rulesDir, err)
+	}
+
+	for _, mdcFile := range mdcFiles {
+		fileName := mdcFile.Name + ".mdc"
+		if err := os.WriteFile(filepath.Join(dir, fileName), []byte(buildMdcContent(mdcFile)), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", fileName, err)
+		}
+		fmt.Printf("  ✓ Wrote %s/%s from %s\n", rulesDir, fileName, source)
+	}
+	return nil
+}
+
+// buildMdcContent renders a rule as an MDC file with frontmatter
+func buildMdcContent(mdcFile MdcFile) string {
+	var content strings.Builder
+	content.WriteString("---\n")
+	if mdcFile.Name != "" {
+		content.WriteString(fmt.Sprintf("name: %s\n", mdcFile.Name))
+	}
+	content.WriteString(strings.TrimPrefix(buildRuleFrontmatter(mdcFile), "---\n"))
+	content.WriteString(mdcFile.Content)
+	return content.String()
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// languageExtensions maps file extensions to the language names used in the
+// languages frontmatter field
+var languageExtensions = map[string]string{
+	".c":      "c",
+	".h":      "c",
+	".cc":     "cpp",
+	".cpp":    "cpp",
+	".hpp":    "cpp",
+	".cs":     "csharp",
+	".css":    "css",
+	".scss":   "css",
+	".dart":   "dart",
+	".ex":     "elixir",
+	".exs":    "elixir",
+	".go":     "go",
+	".html":   "html",
+	".java":   "java",
+	".js":     "javascript",
+	".jsx":    "javascript",
+	".mjs":    "javascript",
+	".cjs":    "javascript",
+	".kt":     "kotlin",
+	".kts":    "kotlin",
+	".php":    "php",
+	".py":     "python",
+	".rb":     "ruby",
+	".rs":     "rust",
+	".scala":  "scala",
+	".sh":     "shell",
+	".sql":    "sql",
+	".swift":  "swift",
+	".ts":     "typescript",
+	".tsx":    "typescript",
+	".mts":    "typescript",
+	".cts":    "typescript",
+	".vue":    "vue",
+	".svelte": "svelte",
+}
+
+// fileLanguage returns the language of a file from its extension, or an empty
+// string if it isn't source code of a known language
+func fileLanguage(path string) string {
+	return languageExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// isKnownLanguage reports whether name, in any case, is a language syncai can
+// detect
+func isKnownLanguage(name string) bool {
+	name = strings.ToLower(name)
+	for _, language := range languageExtensions {
+		if language == name {
+			return true
+		}
+	}
+	return false
+}
+
+// knownLanguages returns the names of the languages syncai can detect, sorted
+func knownLanguages() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, language := range languageExtensions {
+		if !seen[language] {
+			seen[language] = true
+			names = append(names, language)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// usesLanguages reports whether a rule applies to a project containing the
+// given languages: rules without a languages field always do, others need at
+// least one of their languages to be present
+func usesLanguages(mdcFile MdcFile, present map[string]bool) bool {
+	if len(mdcFile.Languages) == 0 {
+		return true
+	}
+	for _, language := range mdcFile.Languages {
+		if present[strings.ToLower(language)] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,300 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Lint checks that can be disabled under lint: in .syncai.yaml
+const (
+	LintLongRule      = "long-rule"
+	LintContradiction = "contradiction"
+	LintNesting       = "nesting"
+	LintMostlyCode    = "mostly-code"
+)
+
+// LintChecks are the checks run by syncai lint, all enabled by default
+var LintChecks = []string{LintLongRule, LintContradiction, LintNesting, LintMostlyCode}
+
+const (
+	// maxRuleTokens is the estimated size above which a single rule is
+	// reported as too long to be followed reliably
+	maxRuleTokens = 2000
+	// maxHeadingLevel and maxListDepth bound the nesting of a rule's content
+	maxHeadingLevel = 4
+	maxListDepth    = 3
+	// minProseLines is the number of lines outside code blocks below which a
+	// rule that is mostly code is reported
+	minProseLines = 3
+)
+
+// lintIssue is an advisory warning about the content of a rule
+type lintIssue struct {
+	Check string
+	Warning
+}
+
+// lintedRule is the content of a rule checked by the linter
+type lintedRule struct {
+	path    string
+	content string
+}
+
+// directive is an instruction such as "always use X" or "never use X" found
+// in a rule, used to detect rules that contradict each other
+type directive struct {
+	rule     string
+	text     string
+	subject  string
+	positive bool
+}
+
+var (
+	positiveDirective = regexp.MustCompile(`(?i)\b(?:always use|must use|prefer)\s+([\w.+#-]+)`)
+	negativeDirective = regexp.MustCompile(`(?i)\b(?:never use|do not use|don't use|must not use|avoid)\s+([\w.+#-]+)`)
+	// directiveStopWords are words after a directive too vague to compare
+	directiveStopWords = map[string]bool{"a": true, "an": true, "the": true, "it": true, "this": true, "that": true, "them": true}
+)
+
+// Lint checks the content of the rules for patterns that make them harder
+// for an LLM to follow, such as very long rules or rules that contradict each
+// other. The issues found are advisory, so they are printed without failing.
+func Lint(opts BuildOptions) error {
+	opts.readOnly = true
+	config, err := loadBuildConfig(opts)
+	if err != nil {
+		return err
+	}
+	reportWarnings(config.warningOutput(), config.Warnings)
+
+	out := config.output()
+	issues := lintRules(config)
+	if len(issues) == 0 {
+		fmt.Fprintf(out, "✓ No issues found\n")
+		return nil
+	}
+	for _, issue := range issues {
+		fmt.Fprintf(out, "  ⚠ %s [%s]\n", issue.Warning, issue.Check)
+	}
+	fmt.Fprintf(out, "%d issue(s) found\n", len(issues))
+	return nil
+}
+
+// lintRules runs the enabled checks on .cursorrules and each rule file
+func lintRules(config *ProjectConfig) []lintIssue {
+	rules := []lintedRule{}
+	if strings.TrimSpace(config.CursorRules) != "" {
+		rules = append(rules, lintedRule{path: ".cursorrules", content: config.CursorRules})
+	}
+	for _, mdcFile := range config.MdcFiles {
+		path := mdcFile.Path
+		if rel, err := filepath.Rel(config.RootPath, path); err == nil {
+			path = rel
+		}
+		rules = append(rules, lintedRule{path: path, content: mdcFile.Content})
+	}
+
+	enabled := config.lintEnabled()
+	issues := []lintIssue{}
+	for _, rule := range rules {
+		if enabled[LintLongRule] {
+			issues = append(issues, lintLongRule(rule)...)
+		}
+		if enabled[LintNesting] {
+			issues = append(issues, lintNesting(rule)...)
+		}
+		if enabled[LintMostlyCode] {
+			issues = append(issues, lintMostlyCode(rule)...)
+		}
+	}
+	if enabled[LintContradiction] {
+		issues = append(issues, lintContradictions(rules)...)
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Path < issues[j].Path
+	})
+	return issues
+}
+
+// lintEnabled returns the checks enabled by the lint: setting
+func (c *ProjectConfig) lintEnabled() map[string]bool {
+	enabled := map[string]bool{}
+	for _, check := range LintChecks {
+		enabled[check] = true
+	}
+	if c.Settings != nil {
+		for check, on := range c.Settings.Lint {
+			enabled[check] = on
+		}
+	}
+	return enabled
+}
+
+func lintLongRule(rule lintedRule) []lintIssue {
+	tokens := estimateTokens(rule.content)
+	if tokens <= maxRuleTokens {
+		return nil
+	}
+	return []lintIssue{{Check: LintLongRule, Warning: Warning{
+		Path:     rule.path,
+		Message:  fmt.Sprintf("rule is about %d tokens, consider splitting it into rules of at most %d", tokens, maxRuleTokens),
+		Severity: SeverityWarning,
+	}}}
+}
+
+// lintNesting reports headings and lists nested so deeply that the structure
+// of the rule gets hard to follow. Code blocks are skipped.
+func lintNesting(rule lintedRule) []lintIssue {
+	headingLevel, listDepth := 0, 0
+	inFence := false
+	for _, line := range strings.Split(rule.content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if level := len(trimmed) - len(strings.TrimLeft(trimmed, "#")); level > 0 && strings.HasPrefix(trimmed[level:], " ") {
+			headingLevel = max(headingLevel, level)
+			continue
+		}
+		if isListItem(trimmed) {
+			indent := strings.ReplaceAll(line[:len(line)-len(strings.TrimLeft(line, " \t"))], "\t", "  ")
+			listDepth = max(listDepth, len(indent)/2+1)
+		}
+	}
+
+	issues := []lintIssue{}
+	if headingLevel > maxHeadingLevel {
+		issues = append(issues, lintIssue{Check: LintNesting, Warning: Warning{
+			Path:     rule.path,
+			Message:  fmt.Sprintf("headings are nested %d levels deep, consider flattening them to at most %d", headingLevel, maxHeadingLevel),
+			Severity: SeverityInfo,
+		}})
+	}
+	if listDepth > maxListDepth {
+		issues = append(issues, lintIssue{Check: LintNesting, Warning: Warning{
+			Path:     rule.path,
+			Message:  fmt.Sprintf("lists are nested %d levels deep, consider flattening them to at most %d", listDepth, maxListDepth),
+			Severity: SeverityInfo,
+		}})
+	}
+	return issues
+}
+
+// isListItem reports whether the trimmed line starts a list item
+func isListItem(trimmed string) bool {
+	if strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") || strings.HasPrefix(trimmed, "+ ") {
+		return true
+	}
+	digits := len(trimmed) - len(strings.TrimLeft(trimmed, "0123456789"))
+	return digits > 0 && strings.HasPrefix(trimmed[digits:], ". ")
+}
+
+// lintMostlyCode reports rules whose content is mostly code blocks with only
+// a few lines explaining when or how to apply them
+func lintMostlyCode(rule lintedRule) []lintIssue {
+	codeLines, proseLines := 0, 0
+	inFence := false
+	for _, line := range strings.Split(rule.content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			inFence = !inFence
+		case trimmed == "":
+		case inFence:
+			codeLines++
+		case strings.HasPrefix(trimmed, "#"):
+		default:
+			proseLines++
+		}
+	}
+	if codeLines == 0 || proseLines >= minProseLines || codeLines < proseLines*4 {
+		return nil
+	}
+	return []lintIssue{{Check: LintMostlyCode, Warning: Warning{
+		Path:     rule.path,
+		Message:  fmt.Sprintf("rule is mostly code (%d lines of code, %d of guidance), explain when and how to apply it", codeLines, proseLines),
+		Severity: SeverityWarning,
+	}}}
+}
+
+// lintContradictions reports rules that both require and forbid the same
+// thing, such as "always use semicolons" and "never use semicolons". The
+// check only compares the word after the directive, so it is a heuristic.
+func lintContradictions(rules []lintedRule) []lintIssue {
+	bySubject := map[string][]directive{}
+	for _, rule := range rules {
+		for _, d := range ruleDirectives(rule) {
+			bySubject[d.subject] = append(bySubject[d.subject], d)
+		}
+	}
+
+	subjects := make([]string, 0, len(bySubject))
+	for subject := range bySubject {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+
+	issues := []lintIssue{}
+	for _, subject := range subjects {
+		var positive, negative *directive
+		for i, d := range bySubject[subject] {
+			if d.positive && positive == nil {
+				positive = &bySubject[subject][i]
+			} else if !d.positive && negative == nil {
+				negative = &bySubject[subject][i]
+			}
+		}
+		if positive == nil || negative == nil {
+			continue
+		}
+		other := "itself"
+		if positive.rule != negative.rule {
+			other = positive.rule
+		}
+		issues = append(issues, lintIssue{Check: LintContradiction, Warning: Warning{
+			Path:     negative.rule,
+			Message:  fmt.Sprintf("%q may contradict %q in %s", negative.text, positive.text, other),
+			Severity: SeverityWarning,
+		}})
+	}
+	return issues
+}
+
+// ruleDirectives returns the directives found outside the code blocks of rule
+func ruleDirectives(rule lintedRule) []directive {
+	directives := []directive{}
+	inFence := false
+	for _, line := range strings.Split(rule.content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		for _, match := range positiveDirective.FindAllStringSubmatch(line, -1) {
+			directives = appendDirective(directives, rule.path, match, true)
+		}
+		for _, match := range negativeDirective.FindAllStringSubmatch(line, -1) {
+			directives = appendDirective(directives, rule.path, match, false)
+		}
+	}
+	return directives
+}
+
+// appendDirective adds the directive of a regexp match unless its subject is
+// too vague to compare
+func appendDirective(directives []directive, rule string, match []string, positive bool) []directive {
+	subject := strings.ToLower(strings.TrimRight(match[1], ".,"))
+	if subject == "" || directiveStopWords[subject] {
+		return directives
+	}
+	return append(directives, directive{rule: rule, text: strings.TrimRight(match[0], ".,"), subject: subject, positive: positive})
+}
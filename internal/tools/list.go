@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// List prints each rule with the globs it applies to and the files of the
+// project they match, leaving out the files ignored by .cursorignore. The
+// file lists are informational; tools that scope rules by glob match files
+// themselves.
+func List(opts BuildOptions) error {
+	opts.readOnly = true
+	config, err := loadBuildConfig(opts)
+	if err != nil {
+		return err
+	}
+	reportWarnings(config.warningOutput(), config.Warnings)
+
+	files, warnings, err := loadProjectFiles(config.RootPath)
+	if err != nil {
+		return err
+	}
+	reportWarnings(config.warningOutput(), warnings)
+
+	out := config.output()
+	if len(config.MdcFiles) == 0 {
+		fmt.Fprintf(out, "No rules found\n")
+		return nil
+	}
+	for _, mdcFile := range config.sortRules(config.MdcFiles) {
+		path := mdcFile.Path
+		if rel, err := filepath.Rel(config.RootPath, path); err == nil {
+			path = rel
+		}
+		fmt.Fprintf(out, "%s (%s)\n", mdcFile.Name, path)
+
+		globs := mdcFile.RootGlobs
+		switch {
+		case len(globs) == 0 && mdcFile.AlwaysApply:
+			fmt.Fprintf(out, "  always applied\n")
+			continue
+		case len(globs) == 0:
+			fmt.Fprintf(out, "  no globs, applied when relevant to the task\n")
+			continue
+		}
+		fmt.Fprintf(out, "  globs: %s\n", strings.Join(globs, ", "))
+
+		matched, ignored := files.matching(globs)
+		summary := fmt.Sprintf("  %d file(s)", len(matched))
+		if ignored > 0 {
+			summary += fmt.Sprintf(", %d more ignored by %s", ignored, CursorIgnoreFileName)
+		}
+		fmt.Fprintf(out, "%s\n", summary)
+		for _, file := range matched {
+			fmt.Fprintf(out, "    %s\n", file)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// generateRules writes n rule files to the rules directory of a new project
+// and returns the project root
+func generateRules(b testing.TB, n int) string {
+	b.Helper()
+	root := b.TempDir()
+	for i := range n {
+		content := fmt.Sprintf("---\ndescription: Rule %d\nglobs: \"src/pkg%d/**/*.ts\"\n---\n# Rule %d\n\n- Use tabs.\n- Keep functions short.\n", i, i, i)
+		writeRule(b, filepath.Join(root, ".cursor", "rules", fmt.Sprintf("pkg%d", i%10), fmt.Sprintf("rule%d.mdc", i)), content)
+	}
+	return root
+}
+
+func BenchmarkLoadMdcFiles(b *testing.B) {
+	root := generateRules(b, 500)
+	rulesDirs := []string{filepath.Join(root, ".cursor", "rules")}
+	load := func(b *testing.B, cache *ruleCache) {
+		mdcFiles, _, err := loadMdcFiles(root, rulesDirs, DefaultRulesDir, DefaultRuleExtensions, cache)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(mdcFiles) != 500 {
+			b.Fatalf("got %d rules, want 500", len(mdcFiles))
+		}
+	}
+
+	// Serial and parallel parsing side by side, to show what the worker
+	// pool gains on this machine; run with -cpu to try other worker counts
+	for _, workers := range slices.Compact([]int{1, runtime.GOMAXPROCS(0)}) {
+		b.Run(fmt.Sprintf("uncached/workers=%d", workers), func(b *testing.B) {
+			parseWorkers = workers
+			b.Cleanup(func() { parseWorkers = 0 })
+			for b.Loop() {
+				load(b, nil)
+			}
+		})
+	}
+	b.Run("cached", func(b *testing.B) {
+		// Rules are usually older than the window in which their stats
+		// aren't trusted
+		err := filepath.Walk(rulesDirs[0], func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				backdate(b, path)
+			}
+			return err
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		// Fill the cache, then read it again each iteration like a new build
+		cache := newRuleCache(root, BuildOptions{})
+		load(b, cache)
+		cache.save()
+		for b.Loop() {
+			load(b, newRuleCache(root, BuildOptions{}))
+		}
+	})
+}
+
+func TestRulesOrderIsDeterministic(t *testing.T) {
+	root := t.TempDir()
+	rule := func(name string) string {
+		return fmt.Sprintf("---\ndescription: %s rules\nglobs: \"*.ts\"\n---\nFollow %s.\n", name, name)
+	}
+	// Written deepest first, so creation order doesn't match the expected order
+	writeFiles(t, root, map[string]string{
+		"a/b/.cursor/rules/deep.mdc": rule("deep"),
+		"z/.cursor/rules/z.mdc":      rule("z"),
+		"a/.cursor/rules/a.mdc":      rule("a"),
+		".cursor/rules/root.mdc":     rule("root"),
+	})
+	t.Chdir(root)
+	config, err := loadBuildConfig(BuildOptions{NoCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Output = io.Discard
+
+	// Parent folders come before their subfolders, then folders sort by name
+	want := []string{
+		filepath.Join(root, ".cursor", "rules"),
+		filepath.Join(root, "a", ".cursor", "rules"),
+		filepath.Join(root, "z", ".cursor", "rules"),
+		filepath.Join(root, "a", "b", ".cursor", "rules"),
+	}
+	if !slices.Equal(config.RulesDirs, want) {
+		t.Fatalf("rules directories %v, want %v", config.RulesDirs, want)
+	}
+	built := buildFiles(t, config, "claude-code")
+	claude := built["CLAUDE.md"]
+	last := -1
+	for _, name := range []string{"root", "a", "z", "deep"} {
+		i := strings.Index(claude, "### "+name+" rules")
+		if i < 0 || i < last {
+			t.Errorf("%s rules out of order in CLAUDE.md:\n%s", name, claude)
+		}
+		last = i
+	}
+
+	random := rand.New(rand.NewPCG(1, 2))
+	for range 20 {
+		shuffled := slices.Clone(want)
+		random.Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		sortRulesDirs(shuffled)
+		if !slices.Equal(shuffled, want) {
+			t.Fatalf("sorted rules directories %v, want %v", shuffled, want)
+		}
+
+		config.RulesDirs = shuffled
+		if err := config.reloadRules(); err != nil {
+			t.Fatal(err)
+		}
+		if got := buildFiles(t, config, "claude-code"); got["CLAUDE.md"] != claude {
+			t.Fatalf("output changed with shuffled rules:\ngot  %q\nwant %q", got["CLAUDE.md"], claude)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log formats for the build progress output
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// LogFormats lists the supported log formats
+var LogFormats = []string{LogFormatText, LogFormatJSON}
+
+// logWriter turns the progress lines written by the build into structured
+// log records, one per line. Lines starting with ⚠ are logged as warnings,
+// lines starting with ✗ as errors and everything else as info.
+type logWriter struct {
+	logger *slog.Logger
+	mu     *sync.Mutex
+	buf    *bytes.Buffer
+}
+
+func newLogWriter(w io.Writer) *logWriter {
+	return &logWriter{
+		logger: slog.New(slog.NewJSONHandler(w, nil)),
+		mu:     &sync.Mutex{},
+		buf:    &bytes.Buffer{},
+	}
+}
+
+// withTool returns a logWriter adding the tool name to every record
+func (w *logWriter) withTool(tool string) *logWriter {
+	return &logWriter{
+		logger: w.logger.With("tool", tool),
+		mu:     &sync.Mutex{},
+		buf:    &bytes.Buffer{},
+	}
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// Keep the incomplete line until the rest of it is written
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			return len(p), nil
+		}
+		w.logLine(line)
+	}
+}
+
+func (w *logWriter) logLine(line string) {
+	message := strings.TrimSpace(line)
+	if message == "" {
+		return
+	}
+	level := slog.LevelInfo
+	switch {
+	case strings.HasPrefix(message, "⚠"):
+		level = slog.LevelWarn
+	case strings.HasPrefix(message, "✗"):
+		level = slog.LevelError
+	}
+	for _, glyph := range []string{"⚠", "✗", "✓", "→"} {
+		if trimmed, ok := strings.CutPrefix(message, glyph); ok {
+			message = strings.TrimSpace(trimmed)
+			break
+		}
+	}
+	w.log(level, message)
+}
+
+func (w *logWriter) log(level slog.Level, message string, args ...any) {
+	w.logger.Log(context.Background(), level, message, args...)
+}
+
+// logWarnings logs each warning with its file and severity
+func (w *logWriter) logWarnings(warnings []Warning) {
+	for _, warning := range warnings {
+		level := slog.LevelWarn
+		if warning.Severity == SeverityError {
+			level = slog.LevelError
+		}
+		w.log(level, warning.Message, "file", warning.Path, "severity", string(warning.Severity))
+	}
+}
+
+// logBuild logs how long building a tool took and whether it failed
+func (w *logWriter) logBuild(tool string, duration time.Duration, err error) {
+	if err != nil {
+		w.log(slog.LevelError, "build failed", "tool", tool, "duration_ms", duration.Milliseconds(), "error", err.Error())
+		return
+	}
+	w.log(slog.LevelInfo, "build finished", "tool", tool, "duration_ms", duration.Milliseconds())
+}
+
+// warningOutput is where warnings are reported: the structured log when the
+// JSON log format is used, stderr otherwise
+func (c *ProjectConfig) warningOutput() io.Writer {
+	if logger, ok := c.Output.(*logWriter); ok {
+		return logger
+	}
+	return os.Stderr
+}
+
+// logf logs a message at the given level, through the structured log when
+// the JSON log format is used and the standard logger otherwise
+func (c *ProjectConfig) logf(level slog.Level, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if logger, ok := c.Output.(*logWriter); ok {
+		logger.log(level, message)
+		return
+	}
+	log.Print(message)
+}
@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	markdownListItem = regexp.MustCompile(`^([-*+]|\d+[.)])\s+(.*)$`)
+	markdownStrong   = regexp.MustCompile(`\*\*(.+?)\*\*`)
+)
+
+// renderMarkdown converts the generated markdown to HTML for previews. It
+// covers what rules commonly use: frontmatter, headings, code fences, lists,
+// horizontal rules and paragraphs with inline code and bold text. Anything
+// else, such as the tags of the xml style, is shown as escaped text.
+func renderMarkdown(content string) string {
+	var b strings.Builder
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	i := 0
+	if end, _ := frontmatterEnd(lines); end > 0 {
+		fmt.Fprintf(&b, "<pre class=\"frontmatter\">%s</pre>\n", html.EscapeString(strings.Join(lines[1:end], "\n")))
+		i = end + 1
+	}
+
+	var paragraph []string
+	list := ""
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			fmt.Fprintf(&b, "<p>%s</p>\n", renderInline(strings.Join(paragraph, " ")))
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			fmt.Fprintf(&b, "</%s>\n", list)
+			list = ""
+		}
+	}
+	flush := func() {
+		flushParagraph()
+		closeList()
+	}
+
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, "```") {
+			flush()
+			var code []string
+			// A closing fence is only backticks, so text after ``` stays code
+			for i++; i < len(lines) && !isClosingFence(lines[i]); i++ {
+				code = append(code, lines[i])
+			}
+			fmt.Fprintf(&b, "<pre><code>%s</code></pre>\n", html.EscapeString(strings.Join(code, "\n")))
+			continue
+		}
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		if match := markdownHeading.FindStringSubmatch(trimmed); match != nil {
+			flush()
+			level := len(match[1])
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", level, renderInline(match[2]), level)
+			continue
+		}
+		if trimmed == "---" || trimmed == "***" {
+			flush()
+			b.WriteString("<hr>\n")
+			continue
+		}
+		if match := markdownListItem.FindStringSubmatch(trimmed); match != nil {
+			flushParagraph()
+			kind := "ul"
+			if match[1][0] >= '0' && match[1][0] <= '9' {
+				kind = "ol"
+			}
+			if list != kind {
+				closeList()
+				fmt.Fprintf(&b, "<%s>\n", kind)
+				list = kind
+			}
+			fmt.Fprintf(&b, "<li>%s</li>\n", renderInline(match[2]))
+			continue
+		}
+		closeList()
+		paragraph = append(paragraph, trimmed)
+	}
+	flush()
+
+	return b.String()
+}
+
+// renderInline escapes text and renders its inline code spans and bold text
+func renderInline(text string) string {
+	var b strings.Builder
+	for i, part := range strings.Split(text, "`") {
+		escaped := html.EscapeString(part)
+		if i%2 == 1 {
+			fmt.Fprintf(&b, "<code>%s</code>", escaped)
+			continue
+		}
+		b.WriteString(markdownStrong.ReplaceAllString(escaped, "<strong>$1</strong>"))
+	}
+	return b.String()
+}
+
+// isClosingFence reports whether line ends a code fence
+func isClosingFence(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "```") && strings.Trim(trimmed, "`") == ""
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Orphans lists generated files on disk that no tool would generate from the
+// current rules, such as the Roo Code context files of a rule whose
+// description changed. Only the directories tools write their rule files to
+// are searched, and nothing is removed.
+func Orphans(opts BuildOptions) error {
+	opts.readOnly = true
+	config, err := loadBuildConfig(opts)
+	if err != nil {
+		return err
+	}
+	reportWarnings(config.warningOutput(), config.Warnings)
+
+	expected, err := generatedPaths(config)
+	if err != nil {
+		return err
+	}
+	orphans, err := orphanedFiles(config, expected)
+	if err != nil {
+		return err
+	}
+
+	out := config.output()
+	if len(orphans) == 0 {
+		fmt.Fprintf(out, "  ✓ No orphaned generated files\n")
+		return nil
+	}
+	for _, path := range orphans {
+		relPath, _ := filepath.Rel(config.outputRoot(), path)
+		fmt.Fprintf(out, "  ⚠ %s is no longer generated by the current rules\n", relPath)
+	}
+	fmt.Fprintf(out, "%d orphaned file(s); review them and delete the ones you no longer need\n", len(orphans))
+	return nil
+}
+
+// orphanedFiles returns the sorted paths of files in tool output directories
+// under the output root and each --tool-dir that are not in expected
+func orphanedFiles(config *ProjectConfig, expected map[string]bool) ([]string, error) {
+	roots := map[string]bool{config.outputRoot(): true}
+	for _, name := range ToolNames {
+		roots[config.outputDir(name)] = true
+	}
+
+	found := map[string]bool{}
+	for root := range roots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if !info.IsDir() {
+				return nil
+			}
+			if info.Name() == ".git" || info.Name() == ".syncai" {
+				return filepath.SkipDir
+			}
+			if !isOutputDirName(info.Name()) {
+				return nil
+			}
+			// Everything in an output directory, including nested
+			// directories such as external/, was written by a tool
+			err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if info.Mode().IsRegular() && !expected[file] {
+					found[file] = true
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search %s for generated files: %w", root, err)
+		}
+	}
+
+	orphans := make([]string, 0, len(found))
+	for path := range found {
+		orphans = append(orphans, path)
+	}
+	sort.Strings(orphans)
+	return orphans, nil
+}
@@ -0,0 +1,226 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ruleCacheVersion is stored in the cache index; an index written with another
+// version is ignored, so changes to the parser never reuse its entries
+const ruleCacheVersion = 2
+
+// ruleCacheRacyWindow is how recently a file may have been modified for its
+// size and modification time not to be trusted. A file written again within
+// the timestamp resolution of its filesystem can change without its
+// modification time doing so, so recent files are always hashed.
+const ruleCacheRacyWindow = 2 * time.Second
+
+// ruleCache keeps parsed rule files in a single index under .syncai/cache, so
+// builds and watch reloads don't parse unchanged rules again. The index is
+// read once, on first use, and written back by save. A nil cache parses every
+// file.
+type ruleCache struct {
+	path string
+	// readOnly reuses the stored entries without adding or removing any, for
+	// commands that must not modify the project
+	readOnly bool
+
+	load    sync.Once
+	mu      sync.Mutex
+	entries map[string]ruleCacheEntry
+	// changed holds the entries added or refreshed since the index was read,
+	// and used the paths of every rule file parsed through the cache
+	changed map[string]ruleCacheEntry
+	used    map[string]bool
+}
+
+// ruleCacheIndex is stored for the whole cache, gob-encoded as it decodes
+// several times faster than JSON, which would cost more than parsing the rules
+type ruleCacheIndex struct {
+	Version int
+	// Entries holds the parsed rules by the path of their file
+	Entries map[string]ruleCacheEntry
+}
+
+// ruleCacheEntry is the parsed rule stored for one rule file
+type ruleCacheEntry struct {
+	Hash string
+	// Size and ModTime, in nanoseconds, are those of the file when its hash was
+	// taken, or zero if it was modified too recently to rely on them. While
+	// both match, the file isn't read at all.
+	Size        int64
+	ModTime     int64
+	Rule        MdcFile
+	Frontmatter map[string]string
+	Warnings    []Warning
+}
+
+// newRuleCache returns the rule cache of the project at rootPath, or nil with
+// --no-cache
+func newRuleCache(rootPath string, opts BuildOptions) *ruleCache {
+	if opts.NoCache {
+		return nil
+	}
+	return &ruleCache{
+		path:     filepath.Join(CacheDir(rootPath), "rules.gob"),
+		readOnly: opts.readOnly,
+		changed:  map[string]ruleCacheEntry{},
+		used:     map[string]bool{},
+	}
+}
+
+// parse parses the rule file at path like parseMdcFile, returning the cached
+// result when the file hasn't changed since it was stored: its size and
+// modification time are compared first, and its content hash only when they
+// differ. info, if not nil, is the file's info from walking its directory,
+// saving a stat for regular files. Rules that read their content from another
+// file with contentFrom aren't cached, as a change to that file wouldn't be
+// noticed.
+func (r *ruleCache) parse(rootPath, path string, info os.FileInfo) (*MdcFile, []Warning, error) {
+	if r == nil {
+		return parseMdcFile(rootPath, path)
+	}
+	r.load.Do(func() { r.entries = r.readIndex() })
+	if info == nil || !info.Mode().IsRegular() {
+		var err error
+		if info, err = os.Stat(path); err != nil {
+			return parseMdcFile(rootPath, path)
+		}
+	}
+
+	r.mu.Lock()
+	entry, ok := r.entries[path]
+	r.used[path] = true
+	r.mu.Unlock()
+	if ok && entry.ModTime != 0 && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() {
+		return entry.rule()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return parseMdcFile(rootPath, path)
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if ok && entry.Hash == hash {
+		// Touched without changes, so only its stats are refreshed
+		r.store(path, entry, info)
+		return entry.rule()
+	}
+
+	mdcFile, warnings, err := parseMdcData(rootPath, path, data)
+	if err != nil || mdcFile.contentFrom != "" {
+		return mdcFile, warnings, err
+	}
+	r.store(path, ruleCacheEntry{
+		Hash:        hash,
+		Rule:        *mdcFile,
+		Frontmatter: mdcFile.frontmatter,
+		Warnings:    warnings,
+	}, info)
+	return mdcFile, warnings, nil
+}
+
+// rule returns a copy of the stored rule and its warnings
+func (e ruleCacheEntry) rule() (*MdcFile, []Warning, error) {
+	mdcFile := e.Rule
+	mdcFile.frontmatter = e.Frontmatter
+	if mdcFile.frontmatter == nil {
+		mdcFile.frontmatter = map[string]string{}
+	}
+	warnings := e.Warnings
+	if warnings == nil {
+		warnings = []Warning{}
+	}
+	return &mdcFile, warnings, nil
+}
+
+// store records the entry of the rule at path, with the stats of its file
+func (r *ruleCache) store(path string, entry ruleCacheEntry, info os.FileInfo) {
+	entry.Size, entry.ModTime = 0, 0
+	if time.Since(info.ModTime()) > ruleCacheRacyWindow {
+		entry.Size, entry.ModTime = info.Size(), info.ModTime().UnixNano()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.entries[path]; ok && old.Size == entry.Size && old.ModTime == entry.ModTime && old.Hash == entry.Hash {
+		return
+	}
+	r.entries[path] = entry
+	r.changed[path] = entry
+}
+
+// readIndex returns the entries of the index file. Missing, unreadable and
+// outdated indexes are treated as empty.
+func (r *ruleCache) readIndex() map[string]ruleCacheEntry {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return map[string]ruleCacheEntry{}
+	}
+	var index ruleCacheIndex
+	if gob.NewDecoder(bytes.NewReader(data)).Decode(&index) != nil || index.Version != ruleCacheVersion || index.Entries == nil {
+		return map[string]ruleCacheEntry{}
+	}
+	return index.Entries
+}
+
+// save writes the entries added since the index was read back to it, and
+// removes those of rule files that no longer exist, so the cache doesn't grow
+// with every rule ever renamed or deleted. The index is read again first so
+// entries saved meanwhile by another load, such as that of included rules,
+// are kept, and it is replaced atomically so concurrent builds never read a
+// partial index. Errors are ignored: the cache only saves work, and a
+// read-only checkout must still build.
+func (r *ruleCache) save() {
+	if r == nil || r.readOnly {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := r.readIndex()
+	modified := len(r.changed) > 0
+	for path, entry := range r.changed {
+		entries[path] = entry
+	}
+	for path := range entries {
+		if r.used[path] {
+			continue
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(entries, path)
+			modified = true
+		}
+	}
+	if !modified {
+		return
+	}
+
+	var data bytes.Buffer
+	if gob.NewEncoder(&data).Encode(ruleCacheIndex{Version: ruleCacheVersion, Entries: entries}) != nil {
+		return
+	}
+	dir := filepath.Dir(r.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(dir, ".rules-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || os.Rename(tmp.Name(), r.path) != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	r.changed = map[string]ruleCacheEntry{}
+}
@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/gob"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// cacheIndex returns the entries stored in the rule cache index of root
+func cacheIndex(t testing.TB, root string) map[string]ruleCacheEntry {
+	t.Helper()
+	return newRuleCache(root, BuildOptions{}).readIndex()
+}
+
+// cacheEntries returns the paths of the rule files stored in the rule cache
+// of root
+func cacheEntries(t testing.TB, root string) []string {
+	t.Helper()
+	return slices.Sorted(maps.Keys(cacheIndex(t, root)))
+}
+
+// writeCacheIndex replaces the rule cache index of root
+func writeCacheIndex(t testing.TB, root string, entries map[string]ruleCacheEntry) {
+	t.Helper()
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(ruleCacheIndex{Version: ruleCacheVersion, Entries: entries}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(CacheDir(root), "rules.gob"), data.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// backdate sets the modification time of the file at path outside the window
+// in which the cache doesn't trust it
+func backdate(t testing.TB, path string) {
+	t.Helper()
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRuleCacheReparsesEditedRule(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, ".cursor", "rules", "style.mdc")
+	writeRule(t, path, "---\ndescription: Style\n---\nUse tabs.\n")
+	backdate(t, path)
+	cache := newRuleCache(root, BuildOptions{})
+
+	mdcFile, _, err := cache.parse(root, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mdcFile.Content != "Use tabs.\n" {
+		t.Fatalf("got content %q", mdcFile.Content)
+	}
+	if entries := cacheEntries(t, root); len(entries) != 0 {
+		t.Fatalf("got %d cache entries before saving, want none", len(entries))
+	}
+	cache.save()
+	entries := cacheIndex(t, root)
+	if len(entries) != 1 {
+		t.Fatalf("got %d cache entries, want 1", len(entries))
+	}
+
+	// Tamper with the entry to tell a cache hit from parsing again. The hash
+	// is wrong too, so a hit also shows the file's stats were enough.
+	entry := entries[path]
+	entry.Rule.Content = "From the cache.\n"
+	entry.Hash = "tampered"
+	writeCacheIndex(t, root, map[string]ruleCacheEntry{path: entry})
+	cache = newRuleCache(root, BuildOptions{})
+	if mdcFile, _, err = cache.parse(root, path, nil); err != nil || mdcFile.Content != "From the cache.\n" {
+		t.Errorf("unchanged rule wasn't read from the cache: got %q, %v", mdcFile.Content, err)
+	}
+
+	// The same size and an old modification time, but a new content hash
+	writeRule(t, path, "---\ndescription: Style\n---\nUse TABS.\n")
+	backdate(t, path)
+	cache = newRuleCache(root, BuildOptions{})
+	if mdcFile, _, err = cache.parse(root, path, nil); err != nil || mdcFile.Content != "Use TABS.\n" {
+		t.Errorf("edited rule wasn't parsed again: got %q, %v", mdcFile.Content, err)
+	}
+}
+
+func TestRuleCacheHashesRecentFiles(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, ".cursor", "rules", "style.mdc")
+	writeRule(t, path, "---\ndescription: Style\n---\nUse tabs.\n")
+	cache := newRuleCache(root, BuildOptions{})
+	if _, _, err := cache.parse(root, path, nil); err != nil {
+		t.Fatal(err)
+	}
+	cache.save()
+	if entry := cacheIndex(t, root)[path]; entry.ModTime != 0 {
+		t.Fatalf("stats of a file modified just now were stored: %+v", entry)
+	}
+
+	// Rewritten with the same size within the same timestamp, only the
+	// content hash tells the change apart
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRule(t, path, "---\ndescription: Style\n---\nUse TABS.\n")
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	cache = newRuleCache(root, BuildOptions{})
+	if mdcFile, _, err := cache.parse(root, path, nil); err != nil || mdcFile.Content != "Use TABS.\n" {
+		t.Errorf("recently edited rule wasn't parsed again: got %q, %v", mdcFile.Content, err)
+	}
+}
+
+func TestRuleCacheReadOnlyCommands(t *testing.T) {
+	root := t.TempDir()
+	writeRule(t, filepath.Join(root, ".cursor", "rules", "style.mdc"), "---\ndescription: Style\nalwaysApply: true\n---\nUse tabs.\n")
+	t.Chdir(root)
+
+	if err := Validate(BuildOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := List(BuildOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Stats(nil, BuildOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if entries := cacheEntries(t, root); len(entries) != 0 {
+		t.Errorf("read-only commands wrote %d cache entries", len(entries))
+	}
+
+	if _, err := loadBuildConfig(BuildOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if entries := cacheEntries(t, root); len(entries) != 1 {
+		t.Errorf("got %d cache entries after loading for a build, want 1", len(entries))
+	}
+}
+
+func TestRuleCachePrune(t *testing.T) {
+	root := t.TempDir()
+	kept := filepath.Join(root, ".cursor", "rules", "kept.mdc")
+	removed := filepath.Join(root, ".cursor", "rules", "removed.mdc")
+	writeRule(t, kept, "---\ndescription: Kept\n---\nUse tabs.\n")
+	writeRule(t, removed, "---\ndescription: Removed\n---\nUse spaces.\n")
+	t.Chdir(root)
+
+	if _, err := loadBuildConfig(BuildOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if entries := cacheEntries(t, root); len(entries) != 2 {
+		t.Fatalf("got %d cache entries, want 2", len(entries))
+	}
+
+	if err := os.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+	// Read-only loads leave the stale entry in place
+	if err := Validate(BuildOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if entries := cacheEntries(t, root); len(entries) != 2 {
+		t.Fatalf("read-only load pruned the cache to %d entries", len(entries))
+	}
+	if _, err := loadBuildConfig(BuildOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	entries := cacheEntries(t, root)
+	if len(entries) != 1 || entries[0] != kept {
+		t.Errorf("cache entries %v, want only the one of %s", entries, kept)
+	}
+}
@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func FuzzParseMdcData(f *testing.F) {
+	seeds, err := filepath.Glob(filepath.Join("..", "..", "examples", "*", ".cursor", "rules", "*.mdc"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, seed := range seeds {
+		data, err := os.ReadFile(seed)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte(""))
+	f.Add([]byte("---"))
+	f.Add([]byte("---\n---\n"))
+	f.Add([]byte("\ufeff---\nglobs:\n- pattern: \"*.ts\"\n  note: x\n---\nbody\n"))
+	f.Add([]byte("Intro\n\n---\n\nMore\n---\n"))
+
+	root := f.TempDir()
+	path := filepath.Join(root, ".cursor", "rules", "fuzz.mdc")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		mdcFile, _, err := parseMdcData(root, path, data)
+		if err != nil {
+			return
+		}
+		if mdcFile.Name == "" {
+			t.Error("parsed rule has no name")
+		}
+		if mdcFile.GlobNotes != nil && len(mdcFile.GlobNotes) != len(mdcFile.Globs) {
+			t.Errorf("got %d glob notes for %d globs", len(mdcFile.GlobNotes), len(mdcFile.Globs))
+		}
+		// Without frontmatter on the first line, the whole file is content
+		content := strings.TrimPrefix(string(data), "\ufeff")
+		if !strings.HasPrefix(content, "---") && mdcFile.Content != content {
+			t.Errorf("content without frontmatter changed:\ngot  %q\nwant %q", mdcFile.Content, content)
+		}
+	})
+}
+
+func TestParseFlag(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   bool
+		wantOk bool
+	}{
+		{value: "true", want: true, wantOk: true},
+		{value: "True", want: true, wantOk: true},
+		{value: "TRUE", want: true, wantOk: true},
+		{value: "yes", want: true, wantOk: true},
+		{value: "Yes", want: true, wantOk: true},
+		{value: "1", want: true, wantOk: true},
+		{value: `"true"`, want: true, wantOk: true},
+		{value: "'yes'", want: true, wantOk: true},
+		{value: "false", wantOk: true},
+		{value: "False", wantOk: true},
+		{value: "no", wantOk: true},
+		{value: "NO", wantOk: true},
+		{value: "0", wantOk: true},
+		{value: `"false"`, wantOk: true},
+		{value: "'0'", wantOk: true},
+		{value: ""},
+		{value: "on"},
+		{value: "maybe"},
+		{value: "2"},
+	}
+	for _, tt := range tests {
+		got, ok := parseFlag(tt.value)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("parseFlag(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestAlwaysApplyWarnings(t *testing.T) {
+	tests := []struct {
+		value       string
+		alwaysApply bool
+		warns       bool
+	}{
+		{value: "yes", alwaysApply: true},
+		{value: "\"False\""},
+		{value: "on", warns: true},
+		{value: "always", warns: true},
+	}
+	for _, tt := range tests {
+		data := "---\ndescription: Rule\nalwaysApply: " + tt.value + "\n---\nUse tabs.\n"
+		mdcFile, warnings, err := parseMdcData(t.TempDir(), "rule.mdc", []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mdcFile.AlwaysApply != tt.alwaysApply {
+			t.Errorf("alwaysApply: %s parsed as %v", tt.value, mdcFile.AlwaysApply)
+		}
+		warned := false
+		for _, warning := range warnings {
+			warned = warned || strings.Contains(warning.Message, "unknown alwaysApply value")
+		}
+		if warned != tt.warns {
+			t.Errorf("alwaysApply: %s warned %v, want %v", tt.value, warned, tt.warns)
+		}
+	}
+}
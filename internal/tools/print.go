@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// Print builds a single AI tool in memory and writes the generated content to
+// w instead of to files. When the tool generates several files, each one is
+// preceded by a header with its path.
+func Print(name string, opts BuildOptions, w io.Writer) error {
+	opts.readOnly = true
+	config, err := loadBuildConfig(opts)
+	if err != nil {
+		return err
+	}
+	reportWarnings(config.warningOutput(), config.Warnings)
+
+	tools, err := resolveTools(config, []string{name})
+	if err != nil {
+		return err
+	}
+	if config, err = config.applyTransformCmd(); err != nil {
+		return err
+	}
+
+	memory, err := buildInMemory(config, tools[0])
+	if err != nil {
+		return &BuildError{Errors: []*ToolError{{Tool: name, Err: err}}}
+	}
+
+	paths := memory.Paths()
+	if len(paths) == 0 {
+		return fmt.Errorf("%s generates no files from the current rules", name)
+	}
+	for i, path := range paths {
+		data, _ := memory.ReadFile(path)
+		if len(paths) > 1 {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			relPath, _ := filepath.Rel(config.outputRoot(), path)
+			fmt.Fprintf(w, "==> %s <==\n", filepath.ToSlash(relPath))
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
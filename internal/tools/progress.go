@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// progressReporter serializes the progress output of tools building
+// concurrently. Each tool writes to its own buffer, which is written out in
+// one piece once the tool and every tool before it have finished, so lines of
+// different tools never interleave and tools are always reported in the same
+// order, however long each build takes.
+type progressReporter struct {
+	mu    sync.Mutex
+	out   io.Writer
+	tools []*toolProgress
+	// next is the index of the first tool whose output isn't written yet
+	next int
+}
+
+func newProgressReporter(out io.Writer, tools int) *progressReporter {
+	r := &progressReporter{out: out}
+	for range tools {
+		r.tools = append(r.tools, &toolProgress{reporter: r})
+	}
+	return r
+}
+
+// toolProgress buffers the output of a single tool's build
+type toolProgress struct {
+	reporter *progressReporter
+	buf      bytes.Buffer
+	done     bool
+}
+
+// forTool returns the writer the build of the i-th tool writes its progress to
+func (r *progressReporter) forTool(i int) *toolProgress {
+	return r.tools[i]
+}
+
+func (p *toolProgress) Write(data []byte) (int, error) {
+	return p.buf.Write(data)
+}
+
+// flush marks the tool's build as finished and writes out the output of the
+// finished tools no earlier tool is still waiting for
+func (p *toolProgress) flush() {
+	r := p.reporter
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p.done = true
+	for r.next < len(r.tools) && r.tools[r.next].done {
+		r.out.Write(r.tools[r.next].buf.Bytes())
+		r.tools[r.next].buf.Reset()
+		r.next++
+	}
+}
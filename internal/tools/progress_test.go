@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestProgressReporterOrder(t *testing.T) {
+	var out bytes.Buffer
+	reporter := newProgressReporter(&out, 3)
+	for i := range 3 {
+		fmt.Fprintf(reporter.forTool(i), "tool %d\n", i)
+	}
+
+	reporter.forTool(2).flush()
+	if out.Len() != 0 {
+		t.Errorf("last tool written before the others finished: %q", out.String())
+	}
+	reporter.forTool(0).flush()
+	if out.String() != "tool 0\n" {
+		t.Errorf("got %q after the first tool finished", out.String())
+	}
+	reporter.forTool(1).flush()
+	if out.String() != "tool 0\ntool 1\ntool 2\n" {
+		t.Errorf("got %q after every tool finished", out.String())
+	}
+}
+
+// slowTool is a tool whose build prints its name after a delay
+type slowTool struct {
+	name  string
+	delay time.Duration
+}
+
+func (s slowTool) Name() string             { return s.name }
+func (s slowTool) Capabilities() ToolConfig { return ToolConfig{} }
+
+func (s slowTool) Build(config *ProjectConfig) error {
+	time.Sleep(s.delay)
+	fmt.Fprintf(config.output(), "Building %s\n", s.name)
+	return nil
+}
+
+func (s slowTool) Import(ctx context.Context, rootPath string) (*ProjectConfig, error) {
+	return &ProjectConfig{RootPath: rootPath}, nil
+}
+
+func TestBuildOnceReportsToolsInOrder(t *testing.T) {
+	var out bytes.Buffer
+	config := testConfig(t.TempDir())
+	config.Output = &out
+	// The first tool finishes last
+	tools := []AITool{
+		slowTool{name: "first", delay: 50 * time.Millisecond},
+		slowTool{name: "second", delay: 10 * time.Millisecond},
+		slowTool{name: "third"},
+	}
+
+	if err := buildOnce(config, tools); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Building first\nBuilding second\nBuilding third\n"; out.String() != want {
+		t.Errorf("got progress %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// generatedBanner marks generated files so they aren't edited by hand
+const generatedBanner = "AUTO-GENERATED BY syncai — DO NOT EDIT"
+
+// addBanner inserts the generated banner into content in a form suited to the
+// file type. It is placed after any frontmatter so the frontmatter stays the
+// first thing in the file. Formats without comments, such as JSON, are left as is.
+func addBanner(path, content string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return content
+	}
+
+	banner := "<!-- " + generatedBanner + " -->\n\n"
+	if strings.HasPrefix(content, "---\n") {
+		if end := strings.Index(content[4:], "\n---\n"); end >= 0 {
+			split := 4 + end + len("\n---\n")
+			return content[:split] + "\n" + banner + strings.TrimLeft(content[split:], "\n")
+		}
+	}
+	return banner + content
+}
+
+// stripBanner removes the generated banner added by addBanner, so imported
+// content doesn't collect another banner on every build
+func stripBanner(content string) string {
+	return strings.Replace(content, "<!-- "+generatedBanner+" -->\n\n", "", 1)
+}
+
+// bannerWriter adds the generated banner to every file before writing it
+type bannerWriter struct {
+	FileWriter
+}
+
+func (w bannerWriter) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return w.FileWriter.WriteFile(path, []byte(addBanner(path, string(data))), perm)
+}
+
+// readonlyWriter writes every file without write permission
+type readonlyWriter struct {
+	FileWriter
+}
+
+func (w readonlyWriter) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return w.FileWriter.WriteFile(path, data, perm&^0222)
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// pruneOutputs removes the files generated for tools that are not being built,
+// returning the removals it made or, in a dry run, would make. Only files the
+// tool itself would generate from the current rules are removed, so nothing
+// outside syncai's outputs is ever deleted.
+func pruneOutputs(config *ProjectConfig, tools []AITool) ([]plannedOperation, error) {
+	removals := []plannedOperation{}
+	targeted := map[string]bool{}
+	for _, tool := range tools {
+		targeted[tool.Name()] = true
+	}
+
+	for _, name := range ToolNames {
+		if targeted[name] {
+			continue
+		}
+
+		tool, err := createTool(name)
+		if err != nil {
+			return nil, err
+		}
+		memory, err := buildInMemory(config, tool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine outputs of %s: %w", name, err)
+		}
+
+		for _, path := range memory.Paths() {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			relPath, _ := filepath.Rel(config.outputRoot(), path)
+			removals = append(removals, plannedOperation{Tool: name, Path: config.planPath(path), Action: ActionDelete, Bytes: int(info.Size())})
+			if config.Options.DryRun {
+				fmt.Fprintf(config.output(), "  → Would remove %s (%s is not targeted)\n", relPath, name)
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", relPath, err)
+			}
+			fmt.Fprintf(config.output(), "  ✓ Removed %s (%s is not targeted)\n", relPath, name)
+		}
+
+		if config.Options.DryRun {
+			continue
+		}
+		// Remove directories the tool created once they are empty
+		dirs := memory.Dirs()
+		for i := len(dirs) - 1; i >= 0; i-- {
+			if entries, err := os.ReadDir(dirs[i]); err == nil && len(entries) == 0 {
+				os.Remove(dirs[i])
+			}
+		}
+	}
+
+	return removals, nil
+}
+
+// reportDryRun prints the files a dry run would have written. Files planned
+// with the same content in previous are left out.
+func reportDryRun(config *ProjectConfig, memory *MemoryFileWriter, previous map[string][]byte) {
+	for _, path := range memory.Paths() {
+		relPath, _ := filepath.Rel(config.outputRoot(), path)
+		data, _ := memory.ReadFile(path)
+		if planned, ok := previous[path]; ok && string(planned) == string(data) {
+			continue
+		}
+		action := map[string]string{
+			ActionCreate:    "Would create",
+			ActionUpdate:    "Would update",
+			ActionUnchanged: "Unchanged",
+		}[dryRunAction(path, data)]
+		fmt.Fprintf(config.output(), "  → %s %s (%d bytes)\n", action, relPath, len(data))
+	}
+}
@@ -0,0 +1,275 @@
+package tools
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// includedRules holds the rules fetched from remote sources. They are merged
+// with a lower priority than the project's own rules.
+type includedRules struct {
+	CursorRules string
+	MdcFiles    []MdcFile
+}
+
+// loadIncludes fetches the remote rule sources listed in the build options
+// and merges their rules into the project configuration
+func loadIncludes(config *ProjectConfig) error {
+	sources := []string{}
+	sources = append(sources, config.Options.IncludeRoots...)
+	sources = append(sources, config.Options.IncludeRepos...)
+	if len(sources) == 0 {
+		return nil
+	}
+
+	cacheDir, cleanup, err := config.cacheDir("remote")
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	included := &includedRules{}
+	for i, source := range sources {
+		isRepo := i >= len(config.Options.IncludeRoots)
+		dir, err := fetchRemoteRules(cacheDir, source, isRepo, config.Options.Offline)
+		if err != nil {
+			return fmt.Errorf("failed to include %s: %w", source, err)
+		}
+
+		if cursorRules := readCursorRules(dir); cursorRules != "" {
+			if included.CursorRules != "" {
+				included.CursorRules += "\n\n"
+			}
+			included.CursorRules += cursorRules
+		}
+
+		// Included sources keep Cursor's layout, whatever the project's rules directory
+		rulesDirs := []string{}
+		err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return skipUnreadable(&config.Warnings, path, info, err)
+			}
+			if info.IsDir() && info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if info.IsDir() && isRulesDir(path, DefaultRulesDir) {
+				rulesDirs = append(rulesDirs, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find .cursor/rules directories in %s: %w", source, err)
+		}
+
+		cache := newRuleCache(config.RootPath, config.Options)
+		mdcFiles, warnings, err := loadMdcFiles(dir, rulesDirs, DefaultRulesDir, config.Settings.ruleExtensions(), cache)
+		if err != nil {
+			return err
+		}
+		cache.save()
+		config.Warnings = append(config.Warnings, warnings...)
+		// Included rules apply to the whole project, not to a folder of the cache
+		for i := range mdcFiles {
+			mdcFiles[i].BaseDir = ""
+		}
+		included.MdcFiles = append(included.MdcFiles, mdcFiles...)
+	}
+
+	config.included = included
+	config.CursorRules = included.mergeCursorRules(config.CursorRules)
+	config.MdcFiles = included.mergeMdcFiles(config.MdcFiles, config.rulesDir())
+	return nil
+}
+
+// mergeCursorRules places the included global rules before the project's own
+func (r *includedRules) mergeCursorRules(cursorRules string) string {
+	if r == nil || r.CursorRules == "" {
+		return cursorRules
+	}
+	if cursorRules == "" {
+		return r.CursorRules
+	}
+	return r.CursorRules + "\n\n" + cursorRules
+}
+
+// mergeMdcFiles places the included MDC rules before the project's own, dropping
+// included rules that the project overrides with a rule of the same name.
+// rulesDir is the relative path of the project's rules directories.
+func (r *includedRules) mergeMdcFiles(mdcFiles []MdcFile, rulesDir string) []MdcFile {
+	if r == nil || len(r.MdcFiles) == 0 {
+		return mdcFiles
+	}
+
+	local := map[string]bool{}
+	for _, mdcFile := range mdcFiles {
+		local[ruleKey(mdcFile.Path, rulesDir)] = true
+	}
+
+	merged := []MdcFile{}
+	for _, mdcFile := range r.MdcFiles {
+		if !local[ruleKey(mdcFile.Path, DefaultRulesDir)] {
+			merged = append(merged, mdcFile)
+		}
+	}
+	return append(merged, mdcFiles...)
+}
+
+// ruleKey identifies a rule by its path inside its rules directory, whose
+// relative path is rulesDir
+func ruleKey(path, rulesDir string) string {
+	path = filepath.ToSlash(path)
+	if i := strings.LastIndex(path, rulesDir+"/"); i >= 0 {
+		return path[i+len(rulesDir+"/"):]
+	}
+	return filepath.Base(path)
+}
+
+// fetchRemoteRules downloads a .tar.gz archive or clones a git repository into
+// cacheDir, returning the directory containing the rules. In offline mode the
+// cached copy is used without contacting the remote.
+func fetchRemoteRules(cacheDir, source string, isRepo, offline bool) (string, error) {
+	hash := sha256.Sum256([]byte(source))
+	dir := filepath.Join(cacheDir, hex.EncodeToString(hash[:8]))
+
+	if offline {
+		if _, err := os.Stat(dir); err != nil {
+			return "", fmt.Errorf("no cached copy available in offline mode")
+		}
+		return rulesRoot(dir), nil
+	}
+
+	fetch := fetchArchive
+	if isRepo {
+		fetch = fetchGitRepo
+	}
+	if err := fetch(source, dir); err != nil {
+		return "", err
+	}
+	return rulesRoot(dir), nil
+}
+
+// rulesRoot returns the directory holding the rules of a fetched source. Archives
+// often wrap their content in a single top-level directory, which is skipped.
+func rulesRoot(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return dir
+	}
+	if name := entries[0].Name(); name != ".cursor" && name != ".git" {
+		return filepath.Join(dir, name)
+	}
+	return dir
+}
+
+func fetchGitRepo(repo, dir string) error {
+	var cmd *exec.Cmd
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		cmd = exec.Command("git", "-C", dir, "pull", "--ff-only", "--quiet")
+	} else {
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		cmd = exec.Command("git", "clone", "--depth", "1", "--quiet", repo, dir)
+	}
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func fetchArchive(url, dir string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download: %s", resp.Status)
+	}
+
+	// Extract next to the cache entry, then swap it in so a failed download
+	// never leaves a partially extracted cache behind
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dir), "download-")
+	if err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := extractTarGz(resp.Body, tmpDir); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to replace cached copy: %w", err)
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return fmt.Errorf("failed to replace cached copy: %w", err)
+	}
+	return nil
+}
+
+// extractTarGz extracts the .cursorrules, .cursorrules.d and .cursor/rules files
+// of an archive into dir
+func extractTarGz(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %s is outside the archive root", header.Name)
+		}
+		if !isRulesArchiveEntry(filepath.ToSlash(name), DefaultRulesDir) {
+			continue
+		}
+
+		target := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+		}
+	}
+}
+
+// isRulesArchiveEntry reports whether an archive entry, named with forward
+// slashes, is a .cursorrules or .cursorrules.d file, or a file in a rules
+// directory whose relative path is rulesDir
+func isRulesArchiveEntry(name, rulesDir string) bool {
+	return path.Base(name) == ".cursorrules" || strings.Contains(name, ".cursorrules.d/") || strings.HasPrefix(name, rulesDir+"/") || strings.Contains(name, "/"+rulesDir+"/")
+}
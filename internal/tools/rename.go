@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Rename renames an MDC rule: it updates the name field of the rule, renames
+// its file when the file is named after the rule, and removes generated files
+// that were only produced because of the old name
+func Rename(oldName, newName string, opts BuildOptions) error {
+	config, err := loadBuildConfig(opts)
+	if err != nil {
+		return err
+	}
+	reportWarnings(os.Stderr, config.Warnings)
+
+	var rule *MdcFile
+	for i, mdcFile := range config.MdcFiles {
+		if mdcFile.Name == newName {
+			return fmt.Errorf("a rule named %s already exists: %s", newName, mdcFile.Path)
+		}
+		if mdcFile.Name == oldName {
+			if rule != nil {
+				return fmt.Errorf("rule name %s is ambiguous: %s and %s", oldName, rule.Path, mdcFile.Path)
+			}
+			rule = &config.MdcFiles[i]
+		}
+	}
+	if rule == nil {
+		return fmt.Errorf("rule not found: %s", oldName)
+	}
+
+	before, err := generatedPaths(config)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(rule.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", rule.Path, err)
+	}
+
+	content, renamedField := renameFrontmatter(string(data), newName)
+	if renamedField {
+		if err := os.WriteFile(rule.Path, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to update %s: %w", rule.Path, err)
+		}
+	}
+
+	newPath := rule.Path
+	ext := filepath.Ext(rule.Path)
+	if strings.TrimSuffix(filepath.Base(rule.Path), ext) == oldName {
+		newPath = filepath.Join(filepath.Dir(rule.Path), newName+ext)
+		if _, err := os.Stat(newPath); err == nil {
+			return fmt.Errorf("cannot rename %s: %s already exists", rule.Path, newPath)
+		}
+		if err := os.Rename(rule.Path, newPath); err != nil {
+			return fmt.Errorf("failed to rename %s: %w", rule.Path, err)
+		}
+	}
+
+	if !renamedField && newPath == rule.Path {
+		return fmt.Errorf("rule %s has neither a name field nor a matching file name", oldName)
+	}
+
+	relPath, _ := filepath.Rel(config.RootPath, newPath)
+	fmt.Printf("  ✓ Renamed rule %s to %s (%s)\n", oldName, newName, relPath)
+
+	// Remove outputs that the renamed rule no longer produces
+	config, err = loadBuildConfig(opts)
+	if err != nil {
+		return err
+	}
+	after, err := generatedPaths(config)
+	if err != nil {
+		return err
+	}
+	for path := range before {
+		if after[path] {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		relPath, _ := filepath.Rel(config.outputRoot(), path)
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", relPath, err)
+		}
+		fmt.Printf("  ✓ Removed stale %s\n", relPath)
+	}
+
+	fmt.Printf("  → Run 'syncai build' to regenerate configurations\n")
+	return nil
+}
+
+// renameFrontmatter replaces the value of the name field in the frontmatter,
+// reporting whether the field was present
+func renameFrontmatter(content, newName string) (string, bool) {
+	lines := strings.Split(content, "\n")
+	end, _ := frontmatterEnd(lines)
+	for i := 1; i < end; i++ {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "name:") {
+			lines[i] = "name: " + newName
+			return strings.Join(lines, "\n"), true
+		}
+	}
+	return content, false
+}
+
+// generatedPaths returns the paths of the files every supported tool would generate
+func generatedPaths(config *ProjectConfig) (map[string]bool, error) {
+	paths := map[string]bool{}
+	for _, name := range ToolNames {
+		tool, err := createTool(name)
+		if err != nil {
+			return nil, err
+		}
+		memory, err := buildInMemory(config, tool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine outputs of %s: %w", name, err)
+		}
+		for _, path := range memory.Paths() {
+			paths[path] = true
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// reportSchemaVersion is incremented whenever the layout of the build report
+// changes in a way readers need to know about
+const reportSchemaVersion = 1
+
+// buildReport is the machine-readable summary of a build written by --report
+type buildReport struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	DurationMs    int64           `json:"durationMs"`
+	Tools         []toolReport    `json:"tools"`
+	Warnings      []warningReport `json:"warnings"`
+
+	mu    sync.Mutex
+	files map[string][]fileReport
+}
+
+type toolReport struct {
+	Name  string       `json:"name"`
+	Files []fileReport `json:"files"`
+	Error string       `json:"error,omitempty"`
+}
+
+type fileReport struct {
+	Path   string `json:"path"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type warningReport struct {
+	Path     string   `json:"path"`
+	Message  string   `json:"message"`
+	Severity Severity `json:"severity"`
+}
+
+func newBuildReport() *buildReport {
+	return &buildReport{
+		SchemaVersion: reportSchemaVersion,
+		files:         map[string][]fileReport{},
+	}
+}
+
+// writer returns a FileWriter that records the files the named tool writes
+// before passing them on
+func (r *buildReport) writer(tool string, w FileWriter) FileWriter {
+	return reportingWriter{FileWriter: w, report: r, tool: tool}
+}
+
+// record adds a file written by a tool, replacing an earlier write to the same path
+func (r *buildReport) record(tool, path string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sum := sha256.Sum256(data)
+	file := fileReport{Path: path, Size: len(data), SHA256: hex.EncodeToString(sum[:])}
+	for i, existing := range r.files[tool] {
+		if existing.Path == path {
+			r.files[tool][i] = file
+			return
+		}
+	}
+	r.files[tool] = append(r.files[tool], file)
+}
+
+// write completes the report with the outcome of the build and writes it to
+// path as JSON. Tools, files and warnings are sorted so reports can be diffed.
+func (r *buildReport) write(path string, config *ProjectConfig, tools []AITool, buildErr error, duration time.Duration) error {
+	toolErrors := map[string]string{}
+	if err, ok := buildErr.(*BuildError); ok {
+		for _, toolErr := range err.Errors {
+			toolErrors[toolErr.Tool] = toolErr.Err.Error()
+		}
+	}
+
+	r.DurationMs = duration.Milliseconds()
+	r.Tools = []toolReport{}
+	for _, tool := range tools {
+		files := append([]fileReport{}, r.files[tool.Name()]...)
+		for i := range files {
+			if rel, err := filepath.Rel(config.outputRoot(), files[i].Path); err == nil {
+				files[i].Path = filepath.ToSlash(rel)
+			}
+		}
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].Path < files[j].Path
+		})
+		r.Tools = append(r.Tools, toolReport{Name: tool.Name(), Files: files, Error: toolErrors[tool.Name()]})
+	}
+	sort.Slice(r.Tools, func(i, j int) bool {
+		return r.Tools[i].Name < r.Tools[j].Name
+	})
+
+	r.Warnings = []warningReport{}
+	for _, warning := range config.Warnings {
+		path := warning.Path
+		if rel, err := filepath.Rel(config.RootPath, path); err == nil {
+			path = filepath.ToSlash(rel)
+		}
+		r.Warnings = append(r.Warnings, warningReport{Path: path, Message: warning.Message, Severity: warning.Severity})
+	}
+	sort.SliceStable(r.Warnings, func(i, j int) bool {
+		return r.Warnings[i].Path < r.Warnings[j].Path
+	})
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode build report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write build report: %w", err)
+	}
+	return nil
+}
+
+// reportingWriter records every file written through it in a build report
+type reportingWriter struct {
+	FileWriter
+	report *buildReport
+	tool   string
+}
+
+func (w reportingWriter) WriteFile(path string, data []byte, perm os.FileMode) error {
+	if err := w.FileWriter.WriteFile(path, data, perm); err != nil {
+		return err
+	}
+	w.report.record(w.tool, path, data)
+	return nil
+}
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,111 +10,207 @@ import (
 
 type RooCode struct{}
 
+// rooCodeDir is the directory Roo Code reads its context files from
+const rooCodeDir = ".roocode"
+
 func (r *RooCode) Name() string {
 	return "roo-code"
 }
 
+func (r *RooCode) Capabilities() ToolConfig {
+	return ToolConfig{SupportsConditionalRules: true, SupportsFolderRules: true, OutputDirs: []string{rooCodeDir}}
+}
+
 func (r *RooCode) Build(config *ProjectConfig) error {
-	fmt.Printf("Building Roo Code configuration...\n")
+	fmt.Fprintf(config.output(), "Building Roo Code configuration...\n")
 	
 	// Roo Code uses .roocode directory with context files
-	roocodeDir := filepath.Join(config.RootPath, ".roocode")
+	roocodeDir := filepath.Join(config.outputDir(r.Name()), rooCodeDir)
 	
 	// Create .roocode directory if it doesn't exist
-	if err := os.MkdirAll(roocodeDir, 0755); err != nil {
+	if err := config.writer().MkdirAll(roocodeDir, config.dirMode()); err != nil {
 		return fmt.Errorf("failed to create .roocode directory: %w", err)
 	}
 	
 	// Create global context file
-	if config.CursorRules != "" {
+	globalRules := config.globalRules(r.Name())
+	if globalRules != "" {
 		globalContextPath := filepath.Join(roocodeDir, "global.md")
-		err := os.WriteFile(globalContextPath, []byte("# Global Context\n\n"+config.CursorRules), 0644)
+		if config.Options.NormalizeHeadings {
+			globalRules = normalizeHeadings(globalRules, 2)
+		}
+		err := config.writer().WriteFile(globalContextPath, []byte("# Global Context\n\n"+globalRules), config.fileMode())
 		if err != nil {
 			return fmt.Errorf("failed to write global context: %w", err)
 		}
-		fmt.Printf("  ✓ Generated .roocode/global.md\n")
+		fmt.Fprintf(config.output(), "  ✓ Generated .roocode/global.md\n")
 	}
 	
 	// Create context files for each MDC file
+	capabilities := config.capabilities(r.Name())
 	for i, mdcFile := range config.MdcFiles {
-		contextFile := fmt.Sprintf("context_%d.md", i+1)
+		contextName := fmt.Sprintf("context_%d", i+1)
 		if mdcFile.Description != "" {
 			// Use description as filename (sanitized)
-			contextFile = fmt.Sprintf("%s.md", sanitizeFilename(mdcFile.Description))
+			contextName = sanitizeFilename(mdcFile.Description)
 		}
+		contextName = config.flatPrefix(mdcFile) + contextName
 		
-		contextPath := filepath.Join(roocodeDir, contextFile)
-		
-		var content strings.Builder
-		if mdcFile.Description != "" {
-			content.WriteString(fmt.Sprintf("# %s\n\n", mdcFile.Description))
+		// Place folder rules in the .roocode directory next to their .cursor
+		// directory (or in .roocode itself in the flat layout), and rules
+		// linking outside the project in .roocode/external
+		ruleDir := roocodeDir
+		if mdcFile.External {
+			ruleDir = filepath.Join(roocodeDir, "external")
+		} else if capabilities.SupportsFolderRules {
+			ruleDir = filepath.Join(config.ruleDir(r.Name(), mdcFile), rooCodeDir)
 		}
-		
-		if len(mdcFile.Globs) > 0 {
-			content.WriteString("## File Patterns\n")
-			for _, glob := range mdcFile.Globs {
-				content.WriteString(fmt.Sprintf("- %s\n", glob))
+		if ruleDir != roocodeDir {
+			if err := config.writer().MkdirAll(ruleDir, config.dirMode()); err != nil {
+				return fmt.Errorf("failed to create %s directory: %w", ruleDir, err)
 			}
-			content.WriteString("\n")
+		} else if len(mdcFile.RootGlobs) > 0 {
+			// Globs are relative to the folder the rule is written in
+			mdcFile.Globs = mdcFile.RootGlobs
 		}
 		
-		if mdcFile.AlwaysApply {
-			content.WriteString("**Always Apply:** Yes\n\n")
+		parts := []MdcFile{mdcFile}
+		if config.Options.SplitGlobs && capabilities.SupportsConditionalRules {
+			parts = splitRuleByGlob(mdcFile)
 		}
-		
-		content.WriteString(mdcFile.Content)
-		
-		err := os.WriteFile(contextPath, []byte(content.String()), 0644)
-		if err != nil {
-			return fmt.Errorf("failed to write context file %s: %w", contextFile, err)
+		for j, part := range parts {
+			contextFile := contextName + ".md"
+			if len(parts) > 1 {
+				contextFile = fmt.Sprintf("%s_%d.md", contextName, j+1)
+			}
+			if err := r.writeRule(config, filepath.Join(ruleDir, contextFile), part, capabilities.SupportsConditionalRules); err != nil {
+				return err
+			}
 		}
-		
-		fmt.Printf("  ✓ Generated .roocode/%s\n", contextFile)
 	}
 	
-	if config.CursorRules == "" && len(config.MdcFiles) == 0 {
-		fmt.Printf("  ⚠ No rules found to generate Roo Code configuration\n")
+	if globalRules == "" && len(config.MdcFiles) == 0 {
+		fmt.Fprintf(config.output(), "  ⚠ No rules found to generate Roo Code configuration\n")
+		return config.noRules(r.Name())
 	}
 	
 	return nil
 }
 
-func (r *RooCode) Import(rootPath string) (*ProjectConfig, error) {
-	config := &ProjectConfig{
-		RootPath: rootPath,
+// writeRule writes a single rule as a context file
+func (r *RooCode) writeRule(config *ProjectConfig, contextPath string, mdcFile MdcFile, native bool) error {
+	var content strings.Builder
+	if native {
+		// Keep the rule's targeting in frontmatter so Roo Code can apply it conditionally
+		content.WriteString(buildRuleFrontmatter(mdcFile))
 	}
 	
-	// Read all .md files from .roocode directory
-	roocodeDir := filepath.Join(rootPath, ".roocode")
-	if _, err := os.Stat(roocodeDir); os.IsNotExist(err) {
-		return config, nil
+	if title := ruleTitle(mdcFile); title != "" {
+		content.WriteString(fmt.Sprintf("# %s\n\n", title))
 	}
 	
-	var allContent strings.Builder
+	if !native && len(mdcFile.Globs) > 0 {
+		content.WriteString("## File Patterns\n")
+		for _, glob := range notedGlobs(mdcFile.Globs, mdcFile.GlobNotes) {
+			content.WriteString(fmt.Sprintf("- %s\n", glob))
+		}
+		content.WriteString("\n")
+	}
+	
+	if !native && mdcFile.AlwaysApply {
+		content.WriteString("**Always Apply:** Yes\n\n")
+	}
+	
+	if config.Options.NormalizeHeadings && ruleTitle(mdcFile) != "" {
+		content.WriteString(normalizeHeadings(mdcFile.Content, 2))
+	} else {
+		content.WriteString(mdcFile.Content)
+	}
+	if len(mdcFile.Examples) > 0 || len(mdcFile.References) > 0 {
+		content.WriteString("\n\n")
+		writeRuleSections(&content, mdcFile, 2)
+	}
+	
+	if err := config.writer().WriteFile(contextPath, []byte(content.String()), config.fileMode()); err != nil {
+		return fmt.Errorf("failed to write context file %s: %w", filepath.Base(contextPath), err)
+	}
+	
+	relPath, _ := filepath.Rel(config.outputRoot(), contextPath)
+	fmt.Fprintf(config.output(), "  ✓ Generated %s\n", filepath.ToSlash(relPath))
+	return nil
+}
+
+func (r *RooCode) Import(ctx context.Context, rootPath string) (*ProjectConfig, error) {
+	config := &ProjectConfig{
+		RootPath: rootPath,
+	}
 	
-	err := filepath.Walk(roocodeDir, func(path string, info os.FileInfo, err error) error {
+	// Read every .roocode directory, as folder rules are written next to the
+	// .cursor directory they came from
+	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if err != nil {
-			return err
+			return skipUnreadable(&config.Warnings, path, info, err)
 		}
-		if !info.IsDir() && strings.HasSuffix(path, ".md") {
-			data, err := os.ReadFile(path)
-			if err != nil {
-				return err
-			}
-			allContent.WriteString(string(data))
-			allContent.WriteString("\n\n")
+		if !info.IsDir() || info.Name() != rooCodeDir {
+			return nil
 		}
-		return nil
+		if err := r.importDir(ctx, config, path); err != nil {
+			return err
+		}
+		return filepath.SkipDir
 	})
 	
 	if err != nil {
-		return nil, fmt.Errorf("failed to read .roocode directory: %w", err)
+		return nil, fmt.Errorf("failed to read .roocode directories: %w", err)
 	}
 	
-	config.CursorRules = allContent.String()
 	return config, nil
 }
 
+// importDir reads the global.md of the root .roocode directory as the global
+// rules, and every other .md file in roocodeDir as a rule
+func (r *RooCode) importDir(ctx context.Context, config *ProjectConfig, roocodeDir string) error {
+	return filepath.Walk(roocodeDir, func(path string, info os.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return skipUnreadable(&config.Warnings, path, info, err)
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".md") {
+			return nil
+		}
+		if path == filepath.Join(config.RootPath, rooCodeDir, "global.md") {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return skipUnreadable(&config.Warnings, path, info, err)
+			}
+			config.CursorRules = strings.TrimPrefix(stripBanner(string(data)), "# Global Context\n\n")
+			return nil
+		}
+		
+		// Rules keep their targeting in frontmatter, followed by their description as a heading
+		mdcFile, warnings, err := parseMdcFile(config.RootPath, path)
+		if err != nil {
+			return skipUnreadable(&config.Warnings, path, info, err)
+		}
+		config.Warnings = append(config.Warnings, warnings...)
+		mdcFile.Content = strings.TrimLeft(stripBanner(mdcFile.Content), "\n")
+		if title := ruleTitle(*mdcFile); title != "" {
+			mdcFile.Content = strings.TrimPrefix(mdcFile.Content, fmt.Sprintf("# %s\n\n", title))
+		}
+		if baseDir := filepath.Dir(roocodeDir); baseDir != config.RootPath {
+			mdcFile.BaseDir = baseDir
+		}
+		mdcFile.RootGlobs = rootRelativeGlobs(config.RootPath, filepath.Dir(roocodeDir), mdcFile.Globs)
+		config.MdcFiles = append(config.MdcFiles, *mdcFile)
+		return nil
+	})
+}
+
 func sanitizeFilename(filename string) string {
 	// Replace invalid characters with underscores
 	invalidChars := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|", " "}
@@ -122,4 +219,29 @@ func sanitizeFilename(filename string) string {
 		result = strings.ReplaceAll(result, char, "_")
 	}
 	return result
-}
\ No newline at end of file
+}
+
+// buildRuleFrontmatter renders the targeting of a rule as MDC-style frontmatter
+func buildRuleFrontmatter(mdcFile MdcFile) string {
+	var frontmatter strings.Builder
+	frontmatter.WriteString("---\n")
+	if mdcFile.Description != "" {
+		frontmatter.WriteString(fmt.Sprintf("description: %s\n", mdcFile.Description))
+	}
+	if len(mdcFile.Globs) > 0 {
+		quoted := make([]string, len(mdcFile.Globs))
+		for i, glob := range mdcFile.Globs {
+			quoted[i] = fmt.Sprintf("%q", glob)
+			if i < len(mdcFile.GlobNotes) && mdcFile.GlobNotes[i] != "" {
+				quoted[i] = fmt.Sprintf("{pattern: %q, note: %q}", glob, mdcFile.GlobNotes[i])
+			}
+		}
+		frontmatter.WriteString(fmt.Sprintf("globs: [%s]\n", strings.Join(quoted, ", ")))
+	}
+	frontmatter.WriteString(fmt.Sprintf("alwaysApply: %t\n", mdcFile.AlwaysApply))
+	if mdcFile.Level != "" {
+		frontmatter.WriteString(fmt.Sprintf("level: %s\n", mdcFile.Level))
+	}
+	frontmatter.WriteString("---\n\n")
+	return frontmatter.String()
+}
@@ -0,0 +1,259 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// copyExample copies the project in examples/<name> to a temporary directory
+// and returns its path
+func copyExample(t testing.TB, name string) string {
+	t.Helper()
+	src := filepath.Join("..", "..", "examples", name)
+	dst := t.TempDir()
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+	if err != nil {
+		t.Fatalf("failed to copy example %s: %v", name, err)
+	}
+	return dst
+}
+
+// fileState is the content and modification time of a file in a snapshot
+type fileState struct {
+	data    string
+	modTime time.Time
+}
+
+// snapshotTree records every file under root outside the syncai cache, by
+// path relative to root
+func snapshotTree(t testing.TB, root string) map[string]fileState {
+	t.Helper()
+	files := map[string]fileState{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".syncai" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		files[filepath.ToSlash(rel)] = fileState{data: string(data), modTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to snapshot %s: %v", root, err)
+	}
+	return files
+}
+
+// buildFiles builds a tool in memory and returns the written files by path
+// relative to the project root
+func buildFiles(t testing.TB, config *ProjectConfig, toolName string) map[string]string {
+	t.Helper()
+	tool, err := createTool(toolName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memory, err := buildInMemory(config, tool)
+	if err != nil {
+		t.Fatalf("failed to build %s: %v", toolName, err)
+	}
+	files := map[string]string{}
+	for path, data := range memory.Files() {
+		rel, err := filepath.Rel(config.RootPath, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[filepath.ToSlash(rel)] = string(data)
+	}
+	return files
+}
+
+// writeFiles writes files, by path relative to root, to disk
+func writeFiles(t testing.TB, root string, files map[string]string) {
+	t.Helper()
+	for rel, data := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// assertRoundTrip builds a tool from config, imports the generated files into
+// an empty project with the same tool, and builds again from what was
+// imported. Both builds must write the same files with the same content.
+func assertRoundTrip(t *testing.T, config *ProjectConfig, toolName string) {
+	t.Helper()
+	built := buildFiles(t, config, toolName)
+
+	root := t.TempDir()
+	writeFiles(t, root, built)
+	tool, err := createTool(toolName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := tool.Import(context.Background(), root)
+	if err != nil {
+		t.Fatalf("failed to import %s: %v", toolName, err)
+	}
+	imported.Settings = &Settings{}
+	imported.RulesDir = DefaultRulesDir
+	imported.Options = config.Options
+	imported.Output = io.Discard
+
+	rebuilt := buildFiles(t, imported, toolName)
+	for path, want := range built {
+		if rebuilt[path] != want {
+			t.Errorf("%s changed after import:\ngot  %q\nwant %q", path, rebuilt[path], want)
+		}
+	}
+	for path := range rebuilt {
+		if _, ok := built[path]; !ok {
+			t.Errorf("unexpected file %s after import", path)
+		}
+	}
+}
+
+func TestBuildIsIdempotent(t *testing.T) {
+	root := copyExample(t, "build-test")
+	t.Chdir(root)
+
+	if err := Build(nil, BuildOptions{}); err != nil {
+		t.Fatalf("first build failed: %v", err)
+	}
+	// Backdate everything so a rewrite shows up even within the timer resolution
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for rel := range snapshotTree(t, root) {
+		if err := os.Chtimes(filepath.Join(root, rel), past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := snapshotTree(t, root)
+
+	if err := Build(nil, BuildOptions{}); err != nil {
+		t.Fatalf("second build failed: %v", err)
+	}
+	second := snapshotTree(t, root)
+
+	for rel, before := range first {
+		after, ok := second[rel]
+		switch {
+		case !ok:
+			t.Errorf("%s was removed by the second build", rel)
+		case after.data != before.data:
+			t.Errorf("%s changed on the second build", rel)
+		case !after.modTime.Equal(before.modTime):
+			t.Errorf("%s was rewritten by the second build", rel)
+		}
+	}
+	for rel := range second {
+		if _, ok := first[rel]; !ok {
+			t.Errorf("%s was only written by the second build", rel)
+		}
+	}
+	if _, ok := first["CLAUDE.md"]; !ok || !strings.Contains(first["CLAUDE.md"].data, "API Development Rules") {
+		t.Errorf("expected CLAUDE.md with the example rules, got files %v", len(first))
+	}
+}
+
+func TestImportRoundTrip(t *testing.T) {
+	example := func(t *testing.T) *ProjectConfig {
+		t.Chdir(copyExample(t, "build-test"))
+		config, err := loadBuildConfig(BuildOptions{NoCache: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return config
+	}
+	configs := []struct {
+		name    string
+		load    func(t *testing.T) *ProjectConfig
+		options BuildOptions
+	}{
+		{name: "rules", load: func(t *testing.T) *ProjectConfig { return testConfig(t.TempDir()) }},
+		{name: "example", load: example},
+		{name: "banner", load: example, options: BuildOptions{Banner: true}},
+		{name: "toc", load: example, options: BuildOptions{TOC: true}},
+	}
+	toolNames := []string{"windsurf", "roo-code", "cline", "claude-code", "agents"}
+
+	for _, toolName := range toolNames {
+		for _, tt := range configs {
+			t.Run(toolName+"/"+tt.name, func(t *testing.T) {
+				config := tt.load(t)
+				config.Options = tt.options
+				config.Output = io.Discard
+				assertRoundTrip(t, config, toolName)
+			})
+		}
+	}
+}
+
+// Cursor reads its rules from the sources, so importing must find the rules
+// the build loads
+func TestCursorImportRoundTrip(t *testing.T) {
+	root := copyExample(t, "build-test")
+	t.Chdir(root)
+	config, err := loadBuildConfig(BuildOptions{NoCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := (&Cursor{}).Import(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if imported.CursorRules != config.CursorRules {
+		t.Errorf("imported .cursorrules %q, want %q", imported.CursorRules, config.CursorRules)
+	}
+	if len(imported.MdcFiles) != len(config.MdcFiles) {
+		t.Fatalf("imported %d rules, want %d", len(imported.MdcFiles), len(config.MdcFiles))
+	}
+	for i, want := range config.MdcFiles {
+		got := imported.MdcFiles[i]
+		if got.Path != want.Path || got.Name != want.Name || got.Description != want.Description ||
+			got.AlwaysApply != want.AlwaysApply || got.Content != want.Content ||
+			!slices.Equal(got.Globs, want.Globs) || !slices.Equal(got.RootGlobs, want.RootGlobs) {
+			t.Errorf("imported rule %d differs:\ngot  %+v\nwant %+v", i, got, want)
+		}
+	}
+}
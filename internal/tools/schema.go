@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RuleSchemaPath is the location of the rule metadata schema, relative to the project root
+const RuleSchemaPath = ".syncai/rules.schema.json"
+
+// ruleSchema is the subset of JSON Schema used to validate rule frontmatter
+type ruleSchema struct {
+	// Required lists the frontmatter fields every rule must set
+	Required []string `json:"required"`
+	// Properties constrains individual frontmatter fields
+	Properties map[string]ruleSchemaProperty `json:"properties"`
+}
+
+type ruleSchemaProperty struct {
+	// Type is one of string, boolean or array
+	Type string `json:"type"`
+	// MinItems is the minimum number of items of an array field
+	MinItems int `json:"minItems"`
+}
+
+// loadRuleSchema reads the rule schema of the project, returning nil when
+// there is none
+func loadRuleSchema(rootPath string) (*ruleSchema, error) {
+	path := filepath.Join(rootPath, RuleSchemaPath)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", RuleSchemaPath, err)
+	}
+
+	schema := &ruleSchema{}
+	if err := json.Unmarshal(data, schema); err != nil {
+		return nil, &InvalidError{Err: fmt.Errorf("failed to parse %s: %w", RuleSchemaPath, err)}
+	}
+	for field, property := range schema.Properties {
+		switch property.Type {
+		case "", "string", "boolean", "array":
+		default:
+			return nil, &InvalidError{Err: fmt.Errorf("%s: %s: unsupported type %q", RuleSchemaPath, field, property.Type)}
+		}
+	}
+	return schema, nil
+}
+
+// schemaViolations checks the frontmatter of every rule against the schema
+func schemaViolations(config *ProjectConfig, schema *ruleSchema) []string {
+	fields := make([]string, 0, len(schema.Properties))
+	for field := range schema.Properties {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	problems := []string{}
+	for _, mdcFile := range config.MdcFiles {
+		path := mdcFile.Path
+		if rel, err := filepath.Rel(config.RootPath, path); err == nil {
+			path = rel
+		}
+
+		for _, field := range schema.Required {
+			if _, ok := mdcFile.frontmatter[field]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required field %s", path, field))
+			}
+		}
+		for _, field := range fields {
+			if _, ok := mdcFile.frontmatter[field]; !ok {
+				continue
+			}
+			if problem := schema.Properties[field].check(mdcFile, field); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s: %s %s", path, field, problem))
+			}
+		}
+	}
+	return problems
+}
+
+// check returns a description of how the field of a rule violates the
+// property, or an empty string if it doesn't
+func (p ruleSchemaProperty) check(mdcFile MdcFile, field string) string {
+	raw := mdcFile.frontmatter[field]
+	list, isList := mdcFile.listField(field)
+
+	switch p.Type {
+	case "string":
+		if raw == "" || strings.HasPrefix(raw, "[") {
+			return "must be a string"
+		}
+	case "boolean":
+		if raw != "true" && raw != "false" {
+			return "must be true or false"
+		}
+	case "array":
+		if !isList {
+			return "must be a list"
+		}
+	}
+	if isList && len(list) < p.MinItems {
+		return fmt.Sprintf("must have at least %d item(s)", p.MinItems)
+	}
+	return ""
+}
+
+// listField returns the parsed items of a list frontmatter field
+func (m MdcFile) listField(field string) ([]string, bool) {
+	switch field {
+	case "globs":
+		return m.Globs, true
+	case "examples":
+		return m.Examples, true
+	case "references":
+		return m.References, true
+	}
+	return nil, false
+}
@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// DefaultServeAddr is the address serve listens on unless another is given
+const DefaultServeAddr = "localhost:4173"
+
+// Serve runs a local HTTP server previewing the files each AI tool would
+// generate, rendered as HTML. The files are built in memory, so nothing is
+// written to disk, and a Watcher rebuilds them whenever a rule changes. Open
+// pages reload themselves after each rebuild.
+func Serve(targets []string, addr string, opts BuildOptions) error {
+	config, err := loadBuildConfig(opts)
+	if err != nil {
+		return err
+	}
+	reportWarnings(config.warningOutput(), config.Warnings)
+
+	tools, err := resolveTools(config, targets)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	preview := &previewServer{config: config, tools: tools}
+	server := &http.Server{Handler: preview.handler()}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			config.logf(slog.LevelError, "Preview server failed: %v", err)
+		}
+	}()
+	defer server.Close()
+	fmt.Fprintf(config.output(), "Serving previews on http://%s\n", listener.Addr())
+
+	// Stop on Ctrl+C; a rebuild in progress is allowed to finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	watcher := NewWatcher(config, tools)
+	watcher.MaxDebounce = opts.WatchDebounceMax
+	watcher.buildTools = preview.build
+	return watcher.Start(ctx)
+}
+
+// previewServer serves the files generated by the last in-memory build
+type previewServer struct {
+	config *ProjectConfig
+	tools  []AITool
+
+	mu sync.RWMutex
+	// Generated files by tool name, then by slash-separated path relative
+	// to the output root
+	files map[string]map[string][]byte
+	// Build errors by tool name
+	errors map[string]error
+	// version is incremented by every build, so pages know when to reload
+	version int
+}
+
+// build builds every tool in memory and replaces the served files
+func (p *previewServer) build() error {
+	files := map[string]map[string][]byte{}
+	toolErrors := map[string]error{}
+	buildErr := &BuildError{}
+	for _, tool := range p.tools {
+		memory, err := buildInMemory(p.config, tool)
+		if err != nil {
+			toolErrors[tool.Name()] = err
+			buildErr.Errors = append(buildErr.Errors, &ToolError{Tool: tool.Name(), Err: err})
+			continue
+		}
+		toolFiles := map[string][]byte{}
+		for path, data := range memory.Files() {
+			relPath, err := filepath.Rel(p.config.outputRoot(), path)
+			if err != nil {
+				relPath = path
+			}
+			toolFiles[filepath.ToSlash(relPath)] = data
+		}
+		files[tool.Name()] = toolFiles
+	}
+
+	p.mu.Lock()
+	p.files = files
+	p.errors = toolErrors
+	p.version++
+	p.mu.Unlock()
+
+	if len(buildErr.Errors) > 0 {
+		return buildErr
+	}
+	return nil
+}
+
+func (p *previewServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", p.serveIndex)
+	mux.HandleFunc("GET /files/{tool}/{path...}", p.serveFile)
+	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		fmt.Fprint(w, p.version)
+	})
+	return mux
+}
+
+type previewTool struct {
+	Name  string
+	Files []string
+	Error string
+}
+
+func (p *previewServer) serveIndex(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	tools := make([]previewTool, 0, len(p.tools))
+	for _, tool := range p.tools {
+		entry := previewTool{Name: tool.Name()}
+		if err := p.errors[tool.Name()]; err != nil {
+			entry.Error = err.Error()
+		}
+		for path := range p.files[tool.Name()] {
+			entry.Files = append(entry.Files, path)
+		}
+		sort.Strings(entry.Files)
+		tools = append(tools, entry)
+	}
+	p.render(w, previewIndexTemplate, map[string]any{"Tools": tools})
+}
+
+func (p *previewServer) serveFile(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	tool, path := r.PathValue("tool"), r.PathValue("path")
+	data, ok := p.files[tool][path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	p.render(w, previewFileTemplate, map[string]any{
+		"Tool":    tool,
+		"Path":    path,
+		"Content": template.HTML(renderMarkdown(string(data))),
+	})
+}
+
+// render writes a page, adding the build version for the reload script. The
+// caller holds p.mu.
+func (p *previewServer) render(w http.ResponseWriter, page *template.Template, data map[string]any) {
+	data["Version"] = p.version
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := page.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+const previewLayout = `{{define "head"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>syncai preview</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+pre { background: #f4f4f4; padding: 0.75em; overflow-x: auto; }
+pre.frontmatter { border-left: 3px solid #999; }
+code { background: #f4f4f4; }
+.error { color: #b00; }
+</style>
+<script>
+const version = {{.Version}};
+setInterval(async () => {
+	try {
+		const response = await fetch("/version");
+		if (Number(await response.text()) !== version) location.reload();
+	} catch {}
+}, 1000);
+</script>
+</head>
+<body>
+{{end}}`
+
+var (
+	previewIndexTemplate = template.Must(template.New("index").Parse(previewLayout + `{{template "head" .}}
+<h1>Generated files</h1>
+{{range .Tools}}
+<h2>{{.Name}}</h2>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{$tool := .Name}}
+<ul>
+{{range .Files}}<li><a href="/files/{{$tool}}/{{.}}">{{.}}</a></li>
+{{else}}<li>No files generated</li>
+{{end}}
+</ul>
+{{end}}
+</body>
+</html>`))
+
+	previewFileTemplate = template.Must(template.New("file").Parse(previewLayout + `{{template "head" .}}
+<p><a href="/">All files</a> · {{.Tool}}</p>
+<h1>{{.Path}}</h1>
+{{.Content}}
+</body>
+</html>`))
+)
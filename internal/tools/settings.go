@@ -0,0 +1,348 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SettingsFileName is the name of the optional project settings file
+const SettingsFileName = ".syncai.yaml"
+
+// Settings represents the project settings read from .syncai.yaml
+type Settings struct {
+	// Tools is the default set of tools to build when no target is given
+	Tools []string
+	// ToolSettings holds per-tool settings, keyed by tool name
+	ToolSettings map[string]ToolSettings
+	// RuleExtensions are the file extensions read as rules in .cursor/rules
+	RuleExtensions []string
+	// RulesDir is the relative path of the directories rule files are read
+	// from, instead of .cursor/rules
+	RulesDir string
+	// FolderRuleExclude are globs of folders, relative to the project root,
+	// whose rules are written at the root by tools with folder rules instead of
+	// next to their .cursor directory
+	FolderRuleExclude []string
+	// Prefix and Suffix frame the content of every combined output file,
+	// unless a tool sets its own
+	Prefix string
+	Suffix string
+	// Lint enables or disables checks of syncai lint by name; checks not
+	// listed are enabled
+	Lint map[string]bool
+}
+
+// DefaultRuleExtensions are the rule file extensions used when none are configured
+var DefaultRuleExtensions = []string{".mdc"}
+
+// DefaultRulesDir is where rule files are read from, relative to the project
+// root and to each folder with its own rules
+const DefaultRulesDir = ".cursor/rules"
+
+// rulesDir returns the configured relative path of rules directories
+func (s *Settings) rulesDir() string {
+	if s == nil || s.RulesDir == "" {
+		return DefaultRulesDir
+	}
+	return s.RulesDir
+}
+
+// ruleExtensions returns the configured rule file extensions
+func (s *Settings) ruleExtensions() []string {
+	if s == nil || len(s.RuleExtensions) == 0 {
+		return DefaultRuleExtensions
+	}
+	return s.RuleExtensions
+}
+
+// excludesFolder reports whether folder, relative to the project root with
+// forward slashes, matches one of the FolderRuleExclude globs
+func (s *Settings) excludesFolder(folder string) bool {
+	if s == nil {
+		return false
+	}
+	for _, pattern := range s.FolderRuleExclude {
+		if matchPathGlob(pattern, folder) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPathGlob matches a slash-separated path against a glob in which ** matches
+// any number of path segments, including none
+func matchPathGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
+
+// ToolSettings represents the settings of a single tool in .syncai.yaml
+type ToolSettings struct {
+	// GlobalRules controls whether .cursorrules is included; nil means yes
+	GlobalRules *bool
+	// Transforms are applied in order to the generated content before writing
+	Transforms []string
+	// RequireRules makes the tool's build fail when it has no rules to
+	// generate output from
+	RequireRules bool
+	// Style is the style of the rules in combined output files, StyleMarkdown
+	// or StyleXML, or empty for the default
+	Style string
+	// Version selects one of the tool's output formats, or is empty for the
+	// default
+	Version string
+	// Prefix and Suffix replace the project-wide ones for this tool
+	Prefix string
+	Suffix string
+}
+
+// tool returns the settings of the named tool
+func (s *Settings) tool(name string) ToolSettings {
+	if s == nil {
+		return ToolSettings{}
+	}
+	return s.ToolSettings[name]
+}
+
+// loadSettings reads the settings file. An explicit settingsPath must exist;
+// otherwise .syncai.yaml is read from the project root if present.
+func loadSettings(rootPath, settingsPath string) (*Settings, error) {
+	if settingsPath == "" {
+		settingsPath = filepath.Join(rootPath, SettingsFileName)
+		if _, err := os.Stat(settingsPath); os.IsNotExist(err) {
+			return &Settings{}, nil
+		}
+	}
+
+	data, err := os.ReadFile(settingsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	settings, err := parseSettings(string(data))
+	if err != nil {
+		return nil, &InvalidError{Err: fmt.Errorf("failed to parse %s: %w", settingsPath, err)}
+	}
+	return settings, nil
+}
+
+func parseSettings(data string) (*Settings, error) {
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &Settings{
+		ToolSettings: map[string]ToolSettings{},
+	}
+
+	// tools is either a list of tool names or a map of tool name to settings
+	if toolMap, ok := doc["tools"].(map[string]interface{}); ok {
+		// Keep the default build order, as map keys are unordered
+		for _, name := range ToolNames {
+			if _, ok := toolMap[name]; ok {
+				settings.Tools = append(settings.Tools, name)
+			}
+		}
+		names := make([]string, 0, len(toolMap))
+		for name := range toolMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !IsKnownTool(name) {
+				return nil, fmt.Errorf("tools: unknown tool: %s", name)
+			}
+			toolSettings, err := parseToolSettings(name, toolMap[name])
+			if err != nil {
+				return nil, fmt.Errorf("tools.%s: %w", name, err)
+			}
+			settings.ToolSettings[name] = toolSettings
+		}
+	} else if value, ok := doc["tools"]; ok {
+		tools, err := yamlStringList(value)
+		if err != nil {
+			return nil, fmt.Errorf("tools: %w", err)
+		}
+		for _, tool := range tools {
+			if !IsKnownTool(tool) {
+				return nil, fmt.Errorf("tools: unknown tool: %s", tool)
+			}
+		}
+		settings.Tools = tools
+	}
+
+	if value, ok := doc["ruleExtensions"]; ok {
+		extensions, err := yamlStringList(value)
+		if err != nil {
+			return nil, fmt.Errorf("ruleExtensions: %w", err)
+		}
+		for _, extension := range extensions {
+			if !strings.HasPrefix(extension, ".") {
+				extension = "." + extension
+			}
+			settings.RuleExtensions = append(settings.RuleExtensions, extension)
+		}
+	}
+
+	if value, ok := doc["rulesDir"]; ok {
+		dir, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("rulesDir: expected a string")
+		}
+		if settings.RulesDir, err = cleanRulesDir(dir); err != nil {
+			return nil, fmt.Errorf("rulesDir: %w", err)
+		}
+	}
+
+	if value, ok := doc["folderRuleExclude"]; ok {
+		patterns, err := yamlStringList(value)
+		if err != nil {
+			return nil, fmt.Errorf("folderRuleExclude: %w", err)
+		}
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("folderRuleExclude: invalid glob %q", pattern)
+			}
+		}
+		settings.FolderRuleExclude = patterns
+	}
+
+	if settings.Prefix, err = yamlText(doc, "prefix"); err != nil {
+		return nil, err
+	}
+	if settings.Suffix, err = yamlText(doc, "suffix"); err != nil {
+		return nil, err
+	}
+
+	if value, ok := doc["lint"]; ok {
+		checks, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("lint: expected a mapping of check names to booleans")
+		}
+		settings.Lint = map[string]bool{}
+		for check, value := range checks {
+			if !slices.Contains(LintChecks, check) {
+				return nil, fmt.Errorf("lint: unknown check %s (expected one of %s)", check, strings.Join(LintChecks, ", "))
+			}
+			enabled, err := yamlBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("lint.%s: %w", check, err)
+			}
+			settings.Lint[check] = enabled
+		}
+	}
+
+	return settings, nil
+}
+
+// yamlText reads a text setting, written as a string or as a list of lines
+func yamlText(fields map[string]interface{}, key string) (string, error) {
+	value, ok := fields[key]
+	if !ok {
+		return "", nil
+	}
+	lines, err := yamlStringList(value)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func parseToolSettings(name string, value interface{}) (ToolSettings, error) {
+	toolSettings := ToolSettings{}
+
+	// A tool listed without settings, e.g. "cursor:"
+	if s, ok := value.(string); ok && s == "" {
+		return toolSettings, nil
+	}
+
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return toolSettings, fmt.Errorf("expected a map of settings")
+	}
+
+	if value, ok := fields["globalRules"]; ok {
+		globalRules, err := yamlBool(value)
+		if err != nil {
+			return toolSettings, fmt.Errorf("globalRules: %w", err)
+		}
+		toolSettings.GlobalRules = &globalRules
+	}
+
+	if value, ok := fields["transforms"]; ok {
+		transforms, err := yamlStringList(value)
+		if err != nil {
+			return toolSettings, fmt.Errorf("transforms: %w", err)
+		}
+		for _, transform := range transforms {
+			if _, ok := contentTransforms[transform]; !ok {
+				return toolSettings, fmt.Errorf("transforms: unknown transform %q (available: %s)", transform, strings.Join(transformNames(), ", "))
+			}
+		}
+		toolSettings.Transforms = transforms
+	}
+
+	if value, ok := fields["requireRules"]; ok {
+		requireRules, err := yamlBool(value)
+		if err != nil {
+			return toolSettings, fmt.Errorf("requireRules: %w", err)
+		}
+		toolSettings.RequireRules = requireRules
+	}
+
+	if value, ok := fields["style"]; ok {
+		style, ok := value.(string)
+		if !ok || !IsKnownStyle(style) {
+			return toolSettings, fmt.Errorf("style: expected %s or %s", StyleMarkdown, StyleXML)
+		}
+		toolSettings.Style = style
+	}
+
+	if value, ok := fields["version"]; ok {
+		version, ok := value.(string)
+		if !ok || !IsKnownToolVersion(name, version) {
+			versions := ToolVersions(name)
+			if len(versions) == 0 {
+				return toolSettings, fmt.Errorf("version: %s has a single output format", name)
+			}
+			return toolSettings, fmt.Errorf("version: expected one of %s", strings.Join(versions, ", "))
+		}
+		toolSettings.Version = version
+	}
+
+	var err error
+	if toolSettings.Prefix, err = yamlText(fields, "prefix"); err != nil {
+		return toolSettings, err
+	}
+	if toolSettings.Suffix, err = yamlText(fields, "suffix"); err != nil {
+		return toolSettings, err
+	}
+
+	return toolSettings, nil
+}
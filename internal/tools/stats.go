@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+)
+
+// contentSize describes the size of a piece of generated or source content
+type contentSize struct {
+	Name   string
+	Chars  int
+	Tokens int
+}
+
+func newContentSize(name, content string) contentSize {
+	return contentSize{
+		Name:   name,
+		Chars:  utf8.RuneCountInString(content),
+		Tokens: estimateTokens(content),
+	}
+}
+
+// Stats prints the size of the content each AI tool would generate, and the
+// size of each rule, without writing any files
+func Stats(targets []string, opts BuildOptions) error {
+	opts.readOnly = true
+	config, err := loadBuildConfig(opts)
+	if err != nil {
+		return err
+	}
+	reportWarnings(os.Stderr, config.Warnings)
+
+	tools, err := resolveTools(config, targets)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Generated content per tool:\n")
+	for _, tool := range tools {
+		size, err := generatedSize(config, tool)
+		if err != nil {
+			return fmt.Errorf("failed to build %s: %w", tool.Name(), err)
+		}
+		if size.Chars == 0 {
+			fmt.Printf("  %-12s no generated files\n", tool.Name())
+			continue
+		}
+		fmt.Printf("  %-12s %8d chars  ~%d tokens\n", tool.Name(), size.Chars, size.Tokens)
+	}
+
+	rules := []contentSize{}
+	if config.CursorRules != "" {
+		rules = append(rules, newContentSize(".cursorrules", config.CursorRules))
+	}
+	for _, mdcFile := range config.MdcFiles {
+		name := mdcFile.Path
+		if rel, err := filepath.Rel(config.RootPath, mdcFile.Path); err == nil {
+			name = rel
+		}
+		rules = append(rules, newContentSize(name, mdcFile.Content))
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Chars > rules[j].Chars
+	})
+
+	fmt.Printf("\nRule sizes:\n")
+	if len(rules) == 0 {
+		fmt.Printf("  ⚠ No rules found\n")
+	}
+	for _, rule := range rules {
+		fmt.Printf("  %8d chars  ~%6d tokens  %s\n", rule.Chars, rule.Tokens, rule.Name)
+	}
+
+	return nil
+}
+
+// generatedSize builds a tool into memory and measures the files it produced
+func generatedSize(config *ProjectConfig, tool AITool) (contentSize, error) {
+	memory, err := buildInMemory(config, tool)
+	if err != nil {
+		return contentSize{}, err
+	}
+
+	total := contentSize{Name: tool.Name()}
+	for _, data := range memory.Files() {
+		size := newContentSize("", string(data))
+		total.Chars += size.Chars
+		total.Tokens += size.Tokens
+	}
+	return total, nil
+}
@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ToolConfig describes what an AI tool's configuration format can express
+type ToolConfig struct {
+	// SupportsConditionalRules means rules can be scoped to files by glob in the
+	// tool's own format. Other tools get the globs as a plain text annotation.
+	SupportsConditionalRules bool
+	// SupportsFolderRules means the tool reads rules from directories nested in
+	// the project, so each rule is written next to the .cursor directory it
+	// came from. Other tools get every rule at the project root.
+	SupportsFolderRules bool
+	// OutputDirs are the names of the directories the tool writes its rule
+	// files to. They are never searched for rules.
+	OutputDirs []string
+	// DefaultVersion names the output format described by this ToolConfig,
+	// for tools with several formats
+	DefaultVersion string
+	// Versions holds the capabilities of the tool's other output formats, by
+	// the version name selected with --tool-version
+	Versions map[string]ToolConfig
+}
+
+// Output formats of Cursor
+const (
+	// CursorModern relies on Cursor reading .cursor/rules natively
+	CursorModern = "modern"
+	// CursorLegacy combines every rule into a single .cursorrules file, for
+	// versions of Cursor that predate .cursor/rules
+	CursorLegacy = "legacy"
+)
+
+// GetToolConfigs returns the configuration capabilities of every supported
+// tool, as reported by the tool's Capabilities method
+func GetToolConfigs() map[string]ToolConfig {
+	configs := make(map[string]ToolConfig, len(ToolNames))
+	for _, name := range ToolNames {
+		// Every name in ToolNames has a tool
+		tool, _ := createTool(name)
+		configs[name] = tool.Capabilities()
+	}
+	return configs
+}
+
+// ToolVersions returns the output format versions of a tool, the default
+// first, or nil if the tool has a single format
+func ToolVersions(tool string) []string {
+	config := GetToolConfigs()[tool]
+	if config.DefaultVersion == "" {
+		return nil
+	}
+	versions := []string{}
+	for version := range config.Versions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return append([]string{config.DefaultVersion}, versions...)
+}
+
+// IsKnownToolVersion reports whether version is an output format of tool
+func IsKnownToolVersion(tool, version string) bool {
+	for _, known := range ToolVersions(tool) {
+		if known == version {
+			return true
+		}
+	}
+	return false
+}
+
+// isOutputDirName reports whether name is the name of a directory some tool
+// writes its rule files to
+func isOutputDirName(name string) bool {
+	for _, config := range GetToolConfigs() {
+		for _, dir := range config.OutputDirs {
+			if dir == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// capabilitySummary describes in one line how a tool's output, in the version
+// it is built with, represents the rule features it can't express natively
+func (c *ProjectConfig) capabilitySummary(name string) string {
+	capabilities := c.capabilities(name)
+	features := []string{"global ✓"}
+	if capabilities.SupportsFolderRules {
+		features = append(features, "folder ✓")
+	} else {
+		features = append(features, "folder ✗ (merged into one file at the root)")
+	}
+	if capabilities.SupportsConditionalRules {
+		features = append(features, "conditional ✓")
+	} else {
+		features = append(features, "conditional ✗ (globs inlined as text)")
+	}
+	return fmt.Sprintf("%s: %s", name, strings.Join(features, ", "))
+}
+
+// splitRuleByGlob splits a rule with several globs into one rule per glob, so
+// tools scoping rules natively only load it for the matching files. Rules with
+// at most one glob are returned as is.
+func splitRuleByGlob(mdcFile MdcFile) []MdcFile {
+	if len(mdcFile.Globs) < 2 {
+		return []MdcFile{mdcFile}
+	}
+	parts := make([]MdcFile, len(mdcFile.Globs))
+	for i, glob := range mdcFile.Globs {
+		part := mdcFile
+		part.Globs = []string{glob}
+		if i < len(mdcFile.RootGlobs) {
+			part.RootGlobs = []string{mdcFile.RootGlobs[i]}
+		}
+		part.GlobNotes = nil
+		if i < len(mdcFile.GlobNotes) && mdcFile.GlobNotes[i] != "" {
+			part.GlobNotes = []string{mdcFile.GlobNotes[i]}
+		}
+		parts[i] = part
+	}
+	return parts
+}
@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// transformDocument is the JSON exchanged with a --transform-cmd command: the
+// parsed rules are written to its stdin, and the rules to build from are read
+// back from its stdout in the same shape
+type transformDocument struct {
+	CursorRules string          `json:"cursorRules"`
+	Rules       []transformRule `json:"rules"`
+}
+
+// transformRule is an MDC rule in a transformDocument. Path is relative to the
+// project root with forward slashes; rules whose path is unchanged keep the
+// folder they were loaded from, while new rules apply to the project root.
+type transformRule struct {
+	Path        string   `json:"path"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Globs       []string `json:"globs,omitempty"`
+	AlwaysApply bool     `json:"alwaysApply"`
+	Scope       string   `json:"scope,omitempty"`
+	Position    string   `json:"position,omitempty"`
+	Level       string   `json:"level,omitempty"`
+	Content     string   `json:"content"`
+	Examples    []string `json:"examples,omitempty"`
+	References  []string `json:"references,omitempty"`
+	Languages   []string `json:"languages,omitempty"`
+}
+
+// applyTransformCmd runs the TransformCmd option on the loaded rules and
+// returns a copy of the config with the rules it printed. Without a command
+// the config is returned as is.
+func (c *ProjectConfig) applyTransformCmd() (*ProjectConfig, error) {
+	if c.Options.TransformCmd == "" {
+		return c, nil
+	}
+
+	doc := transformDocument{CursorRules: c.CursorRules, Rules: []transformRule{}}
+	originals := map[string]MdcFile{}
+	for _, mdcFile := range c.MdcFiles {
+		relPath := filepath.ToSlash(mdcFile.Path)
+		if rel, err := filepath.Rel(c.RootPath, mdcFile.Path); err == nil {
+			relPath = filepath.ToSlash(rel)
+		}
+		originals[relPath] = mdcFile
+		doc.Rules = append(doc.Rules, transformRule{
+			Path:        relPath,
+			Name:        mdcFile.Name,
+			Description: mdcFile.Description,
+			Globs:       mdcFile.Globs,
+			AlwaysApply: mdcFile.AlwaysApply,
+			Scope:       mdcFile.Scope,
+			Position:    mdcFile.Position,
+			Level:       mdcFile.Level,
+			Content:     mdcFile.Content,
+			Examples:    mdcFile.Examples,
+			References:  mdcFile.References,
+			Languages:   mdcFile.Languages,
+		})
+	}
+	input, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rules for --transform-cmd: %w", err)
+	}
+
+	output, err := runTransformCmd(c.RootPath, c.Options.TransformCmd, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var transformed transformDocument
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&transformed); err != nil {
+		return nil, fmt.Errorf("--transform-cmd returned malformed JSON: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("--transform-cmd returned malformed JSON: unexpected data after the document")
+	}
+
+	config := *c
+	config.CursorRules = transformed.CursorRules
+	config.MdcFiles = make([]MdcFile, 0, len(transformed.Rules))
+	for i, rule := range transformed.Rules {
+		mdcFile, err := c.transformedRule(rule, originals)
+		if err != nil {
+			return nil, fmt.Errorf("--transform-cmd returned an invalid rule %d: %w", i+1, err)
+		}
+		config.MdcFiles = append(config.MdcFiles, mdcFile)
+	}
+	return &config, nil
+}
+
+// transformedRule checks a rule returned by the transform command and turns
+// it into an MdcFile, keeping the folder of the rule it replaces
+func (c *ProjectConfig) transformedRule(rule transformRule, originals map[string]MdcFile) (MdcFile, error) {
+	if rule.Name == "" {
+		return MdcFile{}, fmt.Errorf("name is required")
+	}
+	if strings.TrimSpace(rule.Content) == "" {
+		return MdcFile{}, fmt.Errorf("%s: content is empty", rule.Name)
+	}
+	switch rule.Scope {
+	case "", ScopeGlobal, ScopeFolder, ScopeConditional:
+	default:
+		return MdcFile{}, fmt.Errorf("%s: unknown scope %q", rule.Name, rule.Scope)
+	}
+	switch rule.Position {
+	case "", PositionBefore, PositionAfter:
+	default:
+		return MdcFile{}, fmt.Errorf("%s: unknown position %q", rule.Name, rule.Position)
+	}
+	switch rule.Level {
+	case "", LevelMust, LevelShould, LevelMay:
+	default:
+		return MdcFile{}, fmt.Errorf("%s: unknown level %q", rule.Name, rule.Level)
+	}
+
+	relPath := rule.Path
+	if relPath == "" {
+		relPath = rule.Name + ".mdc"
+	}
+	cleaned := filepath.Clean(filepath.FromSlash(relPath))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return MdcFile{}, fmt.Errorf("%s: path %s is outside the project root", rule.Name, rule.Path)
+	}
+
+	mdcFile := MdcFile{
+		Path:        filepath.Join(c.RootPath, cleaned),
+		Name:        rule.Name,
+		Description: rule.Description,
+		Globs:       rule.Globs,
+		AlwaysApply: rule.AlwaysApply,
+		Scope:       rule.Scope,
+		Position:    rule.Position,
+		Level:       rule.Level,
+		Content:     rule.Content,
+		Examples:    rule.Examples,
+		References:  rule.References,
+		Languages:   rule.Languages,
+	}
+	if original, ok := originals[rule.Path]; ok {
+		mdcFile.Path = original.Path
+		mdcFile.BaseDir = original.BaseDir
+		mdcFile.External = original.External
+		mdcFile.frontmatter = original.frontmatter
+	}
+	mdcFile.RootGlobs = rootRelativeGlobs(c.RootPath, mdcFile.BaseDir, mdcFile.Globs)
+	return mdcFile, nil
+}
+
+// runTransformCmd runs command with the shell in dir, writing input to its
+// stdin and returning its stdout. Its stderr is passed through.
+func runTransformCmd(dir, command string, input []byte) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("--transform-cmd %q failed: %w", command, err)
+	}
+	return output, nil
+}
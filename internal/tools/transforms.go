@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// contentTransforms are the built-in transforms that can be applied to a tool's
+// generated content, configured with the transforms setting of the tool
+var contentTransforms = map[string]func(string) string{
+	"strip-html-comments":      stripHTMLComments,
+	"collapse-blank-lines":     collapseBlankLines,
+	"strip-code-fences":        stripCodeFences,
+	"trim-trailing-whitespace": trimTrailingWhitespace,
+	"normalize-whitespace":     normalizeWhitespace,
+}
+
+// transformNames returns the names of the built-in transforms, sorted
+func transformNames() []string {
+	names := make([]string, 0, len(contentTransforms))
+	for name := range contentTransforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->\n?`)
+
+func stripHTMLComments(content string) string {
+	return htmlCommentPattern.ReplaceAllString(content, "")
+}
+
+var blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+
+// collapseBlankLines replaces runs of blank lines with a single blank line
+func collapseBlankLines(content string) string {
+	return blankLinesPattern.ReplaceAllString(content, "\n\n")
+}
+
+// stripCodeFences removes the ``` fence lines, keeping the code itself
+func stripCodeFences(content string) string {
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func trimTrailingWhitespace(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// maxBlankLines is the longest run of blank lines normalizeWhitespace keeps
+const maxBlankLines = 2
+
+// normalizeWhitespace strips trailing whitespace from each line and shortens
+// runs of blank lines to maxBlankLines, so stray whitespace in rules doesn't
+// show up in diffs of generated files. Code blocks are kept verbatim.
+func normalizeWhitespace(content string) string {
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	inCode := false
+	blank := 0
+	for _, line := range lines {
+		if inCode {
+			kept = append(kept, line)
+			inCode = !isClosingFence(line)
+			continue
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank++
+			if blank > maxBlankLines {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		inCode = strings.HasPrefix(strings.TrimSpace(line), "```")
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// transformingWriter applies content transforms to every file before writing it
+type transformingWriter struct {
+	FileWriter
+	transforms []string
+}
+
+func (w transformingWriter) WriteFile(path string, data []byte, perm os.FileMode) error {
+	content := string(data)
+	for _, name := range w.transforms {
+		content = contentTransforms[name](content)
+	}
+	return w.FileWriter.WriteFile(path, []byte(content), perm)
+}
@@ -1,12 +1,21 @@
 package tools
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"io/fs"
 	"os"
+	"os/signal"
+	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -15,53 +24,829 @@ import (
 // A markdown file that contains instructions for the tool.
 type MdcFile struct {
 	Path        string
+	// Name of the rule, from the name field or else the file name without extension
+	Name        string
 	Description string
 	Globs       []string
+	// Globs rewritten to be relative to the project root, for tools that
+	// combine rules from every folder into a single file
+	RootGlobs   []string
+	// GlobNotes holds the note of each glob written as {pattern: ..., note: ...},
+	// or an empty string for globs without one. It is nil when no glob has a
+	// note, and otherwise as long as Globs.
+	GlobNotes []string
 	AlwaysApply bool
+	// Scope is one of ScopeGlobal, ScopeFolder or ScopeConditional, or empty if not specified
+	Scope       string
+	// Position is PositionBefore or PositionAfter, or empty for the default
+	// placement after the global rules
+	Position string
+	// Level is LevelMust, LevelShould or LevelMay, or empty if not specified
+	Level string
 	// Markdown content of the file
 	Content string
+	// Examples and References are emitted in their own sections after the content
+	Examples   []string
+	References []string
+	// Languages limits the rule to projects containing source files of one of
+	// these languages when building with --filter-by-language
+	Languages []string
+	// BaseDir is the directory containing the .cursor directory the rule was
+	// loaded from, or empty for rules that belong to the project root
+	BaseDir string
+	// External is set when the rule file is a symlink to a file outside the
+	// project root
+	External bool
+	// frontmatter holds the raw value of each frontmatter field, by key
+	frontmatter map[string]string
+	// contentFrom is the file the content was read from with contentFrom, if any
+	contentFrom string
 }
 
+// Rule scopes that can be set with the scope frontmatter field
+const (
+	// ScopeGlobal rules are placed alongside the global .cursorrules
+	ScopeGlobal = "global"
+	// ScopeFolder rules apply to the folder containing their .cursor directory
+	ScopeFolder = "folder"
+	// ScopeConditional rules apply to the files matched by their globs
+	ScopeConditional = "conditional"
+)
+
+// Rule positions that can be set with the position frontmatter field
+const (
+	// PositionBefore rules are placed ahead of the global .cursorrules in
+	// combined outputs
+	PositionBefore = "before"
+	// PositionAfter rules keep the default placement after the global rules
+	PositionAfter = "after"
+)
+
+// Rule levels that can be set with the level frontmatter field. Generated
+// files show them next to the rule's description so hard requirements stand
+// out from suggestions.
+const (
+	LevelMust   = "must"
+	LevelShould = "should"
+	LevelMay    = "may"
+)
+
 // ProjectConfig represents the configuration for a project
 type ProjectConfig struct {
 	RootPath     string
 	CursorRules  string
 	MdcFiles     []MdcFile
-	CursorDirs   []string
+	// RulesDirs are the rules directories found in the project, such as
+	// .cursor/rules at the root and in folders with folder rules
+	RulesDirs []string
+	// RulesDir is the relative path of rules directories, DefaultRulesDir
+	// unless configured
+	RulesDir string
+	// Writer receives the generated files; files are written to disk when nil
+	Writer       FileWriter
+	// Output receives build progress messages; defaults to stdout when nil
+	Output       io.Writer
+	Options      BuildOptions
+	Settings     *Settings
+	// Warnings found while loading the rules
+	Warnings []Warning
+	// Languages holds the languages of the source files found in the project
+	Languages map[string]bool
+
+	// Rules fetched from remote sources, kept to merge them again after a reload
+	included *includedRules
+	// report records the files each tool writes when a build report is requested
+	report *buildReport
+	// files lists the project's files for --file-lists, refreshed each build
+	files *projectFiles
+}
+
+// BuildOptions controls how configuration files are generated
+type BuildOptions struct {
+	// ConfigPath is an explicit settings file to use instead of .syncai.yaml
+	ConfigPath string
+	// Watch rebuilds the configuration files whenever a rule changes
+	Watch bool
+	// WatchAll also watches the generated files and restores them when edited by hand
+	WatchAll bool
+	// DedupeContent removes repeated paragraphs from combined output files
+	DedupeContent bool
+	// NoGlobalRules lists tools that are built without .cursorrules
+	NoGlobalRules []string
+	// RequireRules lists tools whose build fails when they have no rules to
+	// generate output from
+	RequireRules []string
+	// Prune removes the outputs of tools that are not being built
+	Prune bool
+	// DryRun reports the files that would be written or removed without touching them
+	DryRun bool
+	// DryRunJSON writes the operations planned by a dry run to stdout as JSON,
+	// moving the progress output to stderr
+	DryRunJSON bool
+	// IncludeRoots are URLs of .tar.gz archives with additional rules
+	IncludeRoots []string
+	// IncludeRepos are git repositories with additional rules
+	IncludeRepos []string
+	// Offline uses the cached copy of remote rules instead of fetching them
+	Offline bool
+	// NoCache fetches into a temporary directory that is removed after
+	// loading, leaving .syncai/cache untouched
+	NoCache bool
+	// readOnly is set by the commands that only read the project, such as
+	// validate and stats, so that loading the rules doesn't write the rule
+	// cache
+	readOnly bool
+	// OutputDirs writes the whole build into each of these directories in
+	// turn, instead of OutputDir
+	OutputDirs []string
+	// OutputDir is the directory generated files are written to instead of the
+	// project root, such as a git worktree of a separate branch
+	OutputDir string
+	// Commit commits the generated files in OutputDir after building
+	Commit bool
+	// RulesDir overrides the relative path of the directories rule files are
+	// read from, .cursor/rules by default
+	RulesDir string
+	// TransformCmd is a shell command that rewrites the loaded rules before
+	// they are built, reading them as JSON on stdin and printing the result
+	TransformCmd string
+	// ManageGitattributes marks the generated files as linguist-generated in
+	// the output directory's .gitattributes
+	ManageGitattributes bool
+	// LogFormat is the format of the progress output: LogFormatText (the
+	// default) or LogFormatJSON for structured log records on stderr
+	LogFormat string
+	// ToolVersions selects the output format version of tools, by tool name,
+	// overriding the version in the settings
+	ToolVersions map[string]string
+	// ToolDirs overrides the output directory of individual tools, by tool name.
+	// Relative directories are resolved against the output directory.
+	ToolDirs map[string]string
+	// Backup saves the previous content of generated files that change to <path>.bak
+	Backup bool
+	// FileMode and DirMode are the permissions of generated files and the
+	// directories created for them, or 0 for the defaults of 0644 and 0755
+	FileMode os.FileMode
+	DirMode  os.FileMode
+	// Style overrides the style of the rules in combined output files for
+	// every tool
+	Style string
+	// OutputLayout is LayoutMirror or LayoutFlat, or empty for LayoutMirror
+	OutputLayout string
+	// KeepWhitespace writes trailing whitespace and long runs of blank lines
+	// from the rules as is, instead of normalizing them
+	KeepWhitespace bool
+	// RulePrefix and RuleSuffix frame the content of every combined output
+	// file, replacing the prefix and suffix settings
+	RulePrefix string
+	RuleSuffix string
+	// RelativeGlobs is GlobsRoot, GlobsAuthored or GlobsAbsolute, or empty
+	// for GlobsRoot
+	RelativeGlobs string
+	// SortRules is SortPath, SortName, SortPriority or SortNone, or empty for
+	// SortPath
+	SortRules string
+	// FileLists lists the files each rule applies to, minus those in
+	// .cursorignore, next to its globs in combined outputs
+	FileLists bool
+	// Banner marks generated files as auto-generated at the top
+	Banner bool
+	// Readonly writes generated files without write permission
+	Readonly bool
+	// AnnotateSources precedes each rule in combined outputs with a comment
+	// naming its source file
+	AnnotateSources bool
+	// Verbose prints how each tool represents the rule features it can't express
+	Verbose bool
+	// Report is the path to write a JSON report of the build to, if set
+	Report string
+	// AllowOutsideRoot keeps rules that are symlinks to files outside the
+	// project root, placing them in an external/ subdirectory for tools with
+	// folder rules. Otherwise they are skipped with a warning.
+	AllowOutsideRoot bool
+	// WatchDebounceMax caps how long continuing changes can delay a rebuild
+	// in watch mode, or 0 for no limit
+	WatchDebounceMax time.Duration
+	// MergeGlobs combines rules that apply to the same files into one rule in
+	// combined output files of tools that can't scope rules natively
+	MergeGlobs bool
+	// NormalizeHeadings demotes headings in rule content so they sit below the
+	// headings generated for each section and rule
+	NormalizeHeadings bool
+	// TOC adds a table of contents linking to each rule to combined outputs
+	TOC bool
+	// SplitGlobs writes a rule with several globs as one rule per glob for
+	// tools that scope rules to files natively
+	SplitGlobs bool
+	// NoMdc builds from the global rules only, leaving out every MDC rule
+	NoMdc bool
+	// FollowSymlinks also searches directories reached through symlinks for
+	// .cursor directories, including .cursor itself being a symlink
+	FollowSymlinks bool
+	// FilterByLanguage leaves out rules whose languages field names no language
+	// found in the project
+	FilterByLanguage bool
+	// NoFolderRules leaves out rules scoped as folder rules and rules loaded
+	// from nested .cursor directories
+	NoFolderRules bool
+	// MaxTokens is the estimated token budget of combined output files, or 0 for no limit
+	MaxTokens int
+	// MaxRules fails the build when more MDC rules than this are found, or 0
+	// for no limit
+	MaxRules int
+}
+
+// outputRoot returns the directory generated files are written to
+func (c *ProjectConfig) outputRoot() string {
+	if c.Options.OutputDir == "" {
+		return c.RootPath
+	}
+	return c.Options.OutputDir
+}
+
+// outputDir returns the directory the named tool writes its files to. A
+// relative --tool-dir is resolved against the output root.
+func (c *ProjectConfig) outputDir(tool string) string {
+	dir, ok := c.Options.ToolDirs[tool]
+	if !ok {
+		return c.outputRoot()
+	}
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(c.outputRoot(), dir)
+}
+
+// ruleDir returns the directory that outputs of a rule are placed in by tools
+// supporting folder rules, mirroring the rule's folder under the tool's output
+// directory. Rules of folders matching folderRuleExclude stay at the root.
+func (c *ProjectConfig) ruleDir(tool string, mdcFile MdcFile) string {
+	if mdcFile.BaseDir == "" || c.Options.OutputLayout == LayoutFlat {
+		return c.outputDir(tool)
+	}
+	rel, err := filepath.Rel(c.RootPath, mdcFile.BaseDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		// Never mirror a folder outside the project root
+		return filepath.Join(c.outputDir(tool), "external")
+	}
+	if c.Settings.excludesFolder(filepath.ToSlash(rel)) {
+		return c.outputDir(tool)
+	}
+	return filepath.Join(c.outputDir(tool), rel)
+}
+
+// flatPrefix returns the prefix for the file name of a folder rule in the flat
+// output layout, which keeps rules with the same name in different folders
+// apart. It is empty for rules of the project root and in the mirror layout.
+func (c *ProjectConfig) flatPrefix(mdcFile MdcFile) string {
+	if c.Options.OutputLayout != LayoutFlat || mdcFile.BaseDir == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(c.RootPath, mdcFile.BaseDir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return sanitizeFilename(filepath.ToSlash(rel)) + "_"
+}
+
+// fileMode returns the permissions to write generated files with
+func (c *ProjectConfig) fileMode() os.FileMode {
+	if c.Options.FileMode == 0 {
+		return 0644
+	}
+	return c.Options.FileMode
+}
+
+// dirMode returns the permissions to create output directories with
+func (c *ProjectConfig) dirMode() os.FileMode {
+	if c.Options.DirMode == 0 {
+		return 0755
+	}
+	return c.Options.DirMode
+}
+
+func (c *ProjectConfig) writer() FileWriter {
+	if c.Writer == nil {
+		return OSFileWriter{}
+	}
+	return c.Writer
+}
+
+// globalRules returns the .cursorrules content to include for the named tool,
+// or an empty string when global rules are disabled for it
+func (c *ProjectConfig) globalRules(tool string) string {
+	for _, name := range c.Options.NoGlobalRules {
+		if name == tool {
+			return ""
+		}
+	}
+	if globalRules := c.Settings.tool(tool).GlobalRules; globalRules != nil && !*globalRules {
+		return ""
+	}
+	return c.CursorRules
+}
+
+// style returns the style of the rules in the named tool's combined output
+func (c *ProjectConfig) style(tool string) string {
+	if c.Options.Style != "" {
+		return c.Options.Style
+	}
+	if style := c.Settings.tool(tool).Style; style != "" {
+		return style
+	}
+	return StyleMarkdown
+}
+
+// framing returns the text that starts and ends the named tool's combined
+// output: the one given on the command line, else the tool's settings, else
+// the project-wide settings
+func (c *ProjectConfig) framing(tool string) (prefix, suffix string) {
+	prefix, suffix = c.Options.RulePrefix, c.Options.RuleSuffix
+	if prefix == "" {
+		if prefix = c.Settings.tool(tool).Prefix; prefix == "" && c.Settings != nil {
+			prefix = c.Settings.Prefix
+		}
+	}
+	if suffix == "" {
+		if suffix = c.Settings.tool(tool).Suffix; suffix == "" && c.Settings != nil {
+			suffix = c.Settings.Suffix
+		}
+	}
+	return prefix, suffix
+}
+
+// toolVersion returns the output format version to build the named tool with:
+// the one given on the command line, else the one in the settings, else the
+// tool's default. It is empty for tools with a single format.
+func (c *ProjectConfig) toolVersion(tool string) string {
+	if version := c.Options.ToolVersions[tool]; version != "" {
+		return version
+	}
+	if version := c.Settings.tool(tool).Version; version != "" {
+		return version
+	}
+	return GetToolConfigs()[tool].DefaultVersion
+}
+
+// capabilities returns the ToolConfig of the named tool for the output format
+// version it is built with
+func (c *ProjectConfig) capabilities(tool string) ToolConfig {
+	config := GetToolConfigs()[tool]
+	if versionConfig, ok := config.Versions[c.toolVersion(tool)]; ok {
+		return versionConfig
+	}
+	return config
+}
+
+// noRules is returned by a tool that has no rules to generate output from. It
+// is ErrNoRules for tools that require rules, and nil otherwise.
+func (c *ProjectConfig) noRules(tool string) error {
+	required := c.Settings.tool(tool).RequireRules
+	for _, name := range c.Options.RequireRules {
+		required = required || name == tool
+	}
+	if required {
+		return ErrNoRules
+	}
+	return nil
+}
+
+// forTool returns the configuration to build the named tool with, writing
+// through the content transforms configured for it and, if requested, adding
+// the generated banner and making files read-only. Files are recorded in the
+// build report as they are finally written.
+func (c *ProjectConfig) forTool(tool string) *ProjectConfig {
+	transforms := c.Settings.tool(tool).Transforms
+	if !c.Options.KeepWhitespace {
+		transforms = append([]string{"normalize-whitespace"}, transforms...)
+	}
+	logger, structured := c.Output.(*logWriter)
+	if len(transforms) == 0 && !c.Options.Banner && !c.Options.Readonly && c.report == nil && !structured {
+		return c
+	}
+
+	writer := c.writer()
+	if c.report != nil {
+		writer = c.report.writer(tool, writer)
+	}
+	if c.Options.Readonly {
+		writer = readonlyWriter{FileWriter: writer}
+	}
+	// The banner is added after the transforms, so they can't strip it
+	if c.Options.Banner {
+		writer = bannerWriter{FileWriter: writer}
+	}
+	if len(transforms) > 0 {
+		writer = transformingWriter{FileWriter: writer, transforms: transforms}
+	}
+
+	toolConfig := *c
+	toolConfig.Writer = writer
+	if structured {
+		toolConfig.Output = logger.withTool(tool)
+	}
+	return &toolConfig
+}
+
+func (c *ProjectConfig) output() io.Writer {
+	if c.Output == nil {
+		return os.Stdout
+	}
+	return c.Output
 }
 
 // AITool represents an AI tool configuration
 type AITool interface {
 	Name() string
+	// Capabilities describes what the tool's configuration format can
+	// express, and the directories it writes to
+	Capabilities() ToolConfig
 	Build(config *ProjectConfig) error
-	Import(rootPath string) (*ProjectConfig, error)
+	// Import reads the tool's existing configuration, stopping early with
+	// ctx.Err() when ctx is canceled
+	Import(ctx context.Context, rootPath string) (*ProjectConfig, error)
 }
 
-// Build builds configuration files for the specified AI tools
-func Build(targets []string, watch bool) error {
-	config, err := loadProjectConfig()
+const (
+	// LayoutMirror writes folder rules next to the folder they came from
+	LayoutMirror = "mirror"
+	// LayoutFlat writes every rule to the tool's output directory, prefixing
+	// the file names of folder rules with their folder
+	LayoutFlat = "flat"
+)
+
+const (
+	// GlobsRoot writes globs in combined outputs relative to the project root
+	GlobsRoot = "root"
+	// GlobsAuthored writes globs in combined outputs as written in the rule,
+	// relative to the folder of a folder rule
+	GlobsAuthored = "authored"
+	// GlobsAbsolute writes globs in combined outputs as absolute paths
+	GlobsAbsolute = "absolute"
+)
+
+// Orders of the rules in combined outputs, set with --sort-rules
+const (
+	// SortPath orders rules by the folder they apply to, parent folders
+	// first, then by their path inside the rules directory
+	SortPath = "path"
+	// SortName orders rules by name
+	SortName = "name"
+	// SortPriority orders rules by level, must first and rules without a
+	// level last, then like SortPath
+	SortPriority = "priority"
+	// SortNone keeps the order the rules were loaded in
+	SortNone = "none"
+)
+
+// ToolNames lists all supported AI tools in their default build order
+var ToolNames = []string{"cursor", "windsurf", "roo-code", "cline", "claude-code", "agents"}
+
+// IsKnownTool reports whether name is a supported AI tool
+func IsKnownTool(name string) bool {
+	for _, toolName := range ToolNames {
+		if toolName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandToolPatterns replaces the glob patterns among targets, such as roo* or
+// *code, with the supported AI tools they match. Each tool appears once, in the
+// order it is first matched. A pattern matching no tool is an error; plain
+// names are kept as they are so they can be validated by the caller.
+func ExpandToolPatterns(targets []string) ([]string, error) {
+	expanded := []string{}
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			expanded = append(expanded, name)
+		}
+	}
+
+	for _, target := range targets {
+		if !strings.ContainsAny(target, "*?[") {
+			add(target)
+			continue
+		}
+		matched := false
+		for _, name := range ToolNames {
+			ok, err := path.Match(target, name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tool pattern %q: %w", target, err)
+			}
+			if ok {
+				matched = true
+				add(name)
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("tool pattern %q matches no tool (valid tools: %s)", target, strings.Join(ToolNames, ", "))
+		}
+	}
+	return expanded, nil
+}
+
+// ToolError represents a build failure of a single AI tool
+type ToolError struct {
+	Tool string
+	Err  error
+}
+
+func (e *ToolError) Error() string {
+	return fmt.Sprintf("failed to build %s: %v", e.Tool, e.Err)
+}
+
+func (e *ToolError) Unwrap() error {
+	return e.Err
+}
+
+// ErrNoRules is returned by Build when the project has no rules to build from
+var ErrNoRules = errors.New("no rules found: add a .cursorrules file or .cursor/rules/*.mdc files")
+
+// InvalidError reports settings or rules that failed to parse or validate
+type InvalidError struct {
+	Err error
+}
+
+func (e *InvalidError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidError) Unwrap() error {
+	return e.Err
+}
+
+// BuildError collects the failures of every AI tool that failed to build
+type BuildError struct {
+	Errors []*ToolError
+}
+
+func (e *BuildError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, toolErr := range e.Errors {
+		messages = append(messages, toolErr.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e *BuildError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, toolErr := range e.Errors {
+		errs = append(errs, toolErr)
+	}
+	return errs
+}
+
+// Build builds configuration files for the specified AI tools. When no
+// targets are given, the tools listed in .syncai.yaml (or all tools) are built.
+func Build(targets []string, opts BuildOptions) error {
+	start := time.Now()
+	config, err := loadBuildConfig(opts)
 	if err != nil {
-		return fmt.Errorf("failed to load project config: %w", err)
+		return err
+	}
+	reportWarnings(config.warningOutput(), config.Warnings)
+
+	// In watch mode rules may still be added, so an empty project is fine
+	if !opts.Watch && config.CursorRules == "" && len(config.MdcFiles) == 0 {
+		return ErrNoRules
+	}
+
+	tools, err := resolveTools(config, targets)
+	if err != nil {
+		return err
+	}
+
+	for _, problem := range duplicateRuleNames(config) {
+		fmt.Fprintf(config.output(), "⚠ %s\n", problem)
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(config.output(), "Rule support per tool:\n")
+		for _, tool := range tools {
+			fmt.Fprintf(config.output(), "  %s\n", config.capabilitySummary(tool.Name()))
+		}
+	}
+
+	if len(opts.OutputDirs) > 0 {
+		return buildOutputDirs(config, tools, start)
+	}
+	return buildOutputs(config, tools, start)
+}
+
+// buildOutputDirs writes the whole build into each of the OutputDirs in turn,
+// reusing the loaded rules, and summarizes the outcome per directory. A failed
+// directory doesn't stop the others from being built.
+func buildOutputDirs(config *ProjectConfig, tools []AITool, start time.Time) error {
+	type outcome struct {
+		dir string
+		err error
+	}
+	outcomes := []outcome{}
+	for _, dir := range config.Options.OutputDirs {
+		dirConfig := *config
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			outcomes = append(outcomes, outcome{dir, fmt.Errorf("invalid output directory: %w", err)})
+			continue
+		}
+		dirConfig.Options.OutputDir = absDir
+		fmt.Fprintf(config.output(), "Building into %s...\n", dir)
+		outcomes = append(outcomes, outcome{dir, buildOutputs(&dirConfig, tools, start)})
+	}
+
+	fmt.Fprintf(config.output(), "Output directories:\n")
+	errs := []error{}
+	for _, o := range outcomes {
+		if o.err != nil {
+			fmt.Fprintf(config.output(), "  ✗ %s: %v\n", o.dir, o.err)
+			errs = append(errs, fmt.Errorf("%s: %w", o.dir, o.err))
+			continue
+		}
+		fmt.Fprintf(config.output(), "  ✓ %s\n", o.dir)
+	}
+	return errors.Join(errs...)
+}
+
+// buildOutputs builds the tools into the configured output directory, then
+// runs the steps that follow a build, such as pruning and committing
+func buildOutputs(config *ProjectConfig, tools []AITool, start time.Time) error {
+	opts := config.Options
+	var err error
+	var dryRunWriter *MemoryFileWriter
+	if opts.DryRun {
+		dryRunWriter = NewMemoryFileWriter()
+		config.Writer = dryRunWriter
+	} else if opts.Backup {
+		config.Writer = OSFileWriter{Backup: true}
+	}
+
+	if opts.Watch {
+		// Stop watching on Ctrl+C; a rebuild in progress is allowed to finish
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		watcher := NewWatcher(config, tools)
+		watcher.MaxDebounce = opts.WatchDebounceMax
+		return watcher.Start(ctx)
+	}
+
+	// The report also tells which tool plans each file of a JSON dry run
+	if opts.Report != "" || opts.DryRunJSON {
+		config.report = newBuildReport()
+	}
+
+	buildErr := buildOnce(config, tools)
+	if opts.Report != "" {
+		if err := config.report.write(opts.Report, config, tools, buildErr, time.Since(start)); err != nil {
+			return err
+		}
+	}
+	if buildErr != nil {
+		return buildErr
+	}
+
+	removals := []plannedOperation{}
+	if opts.Prune {
+		if removals, err = pruneOutputs(config, tools); err != nil {
+			return err
+		}
+	}
+
+	if opts.ManageGitattributes {
+		if err := updateGitattributes(config, tools); err != nil {
+			return err
+		}
+	}
+
+	if opts.Commit && !opts.DryRun {
+		if err := commitOutputs(config, tools); err != nil {
+			return err
+		}
+	}
+
+	if dryRunWriter != nil {
+		if opts.DryRunJSON {
+			return writeDryRunPlan(os.Stdout, config, dryRunWriter, removals)
+		}
+		reportDryRun(config, dryRunWriter, nil)
+	}
+
+	return nil
+}
+
+// loadBuildConfig loads the project configuration for a build with the given
+// options, including any remote rules
+func loadBuildConfig(opts BuildOptions) (*ProjectConfig, error) {
+	config, warnings, err := loadProjectConfig(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project config: %w", err)
+	}
+	config.Options = opts
+	config.Warnings = warnings
+	if opts.LogFormat == LogFormatJSON {
+		// Structured logs go to stderr, leaving stdout to printed content
+		config.Output = newLogWriter(os.Stderr)
+	} else if opts.DryRun && opts.DryRunJSON {
+		// Keep stdout for the JSON plan
+		config.Output = os.Stderr
+	}
+	if opts.OutputDir != "" {
+		if config.Options.OutputDir, err = filepath.Abs(opts.OutputDir); err != nil {
+			return nil, fmt.Errorf("invalid output directory: %w", err)
+		}
+	}
+
+	if err := loadIncludes(config); err != nil {
+		return nil, err
+	}
+	config.Warnings = append(config.Warnings, config.filterMdcFiles()...)
+
+	return config, nil
+}
+
+// filterMdcFiles removes the rules left out by the NoMdc, NoFolderRules and
+// FilterByLanguage options, and rules outside the project root unless
+// AllowOutsideRoot is set. A warning is returned for each rule skipped for
+// being outside the root.
+func (c *ProjectConfig) filterMdcFiles() []Warning {
+	if c.Options.NoMdc {
+		c.MdcFiles = nil
+		return nil
+	}
+	warnings := []Warning{}
+	mdcFiles := []MdcFile{}
+	for _, mdcFile := range c.MdcFiles {
+		if mdcFile.External && !c.Options.AllowOutsideRoot {
+			warnings = append(warnings, Warning{Path: mdcFile.Path, Message: "skipped rule linking outside the project root (use --allow-outside-root to include it)", Severity: SeverityWarning})
+			continue
+		}
+		if c.Options.NoFolderRules && (mdcFile.Scope == ScopeFolder || (mdcFile.BaseDir != "" && mdcFile.BaseDir != c.RootPath)) {
+			continue
+		}
+		if c.Options.FilterByLanguage && !usesLanguages(mdcFile, c.Languages) {
+			continue
+		}
+		mdcFiles = append(mdcFiles, mdcFile)
+	}
+	c.MdcFiles = mdcFiles
+	return warnings
+}
+
+// isOutsideRoot reports whether path, with symlinks resolved, is outside rootPath
+func isOutsideRoot(rootPath, path string) bool {
+	realRoot, err := filepath.EvalSymlinks(rootPath)
+	if err != nil {
+		return false
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(realRoot, realPath)
+	return err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveTools creates the tools to build. When no targets are given, it falls
+// back to the tools declared in .syncai.yaml, then to every tool.
+func resolveTools(config *ProjectConfig, targets []string) ([]AITool, error) {
+	if len(targets) == 0 {
+		targets = config.Settings.Tools
+	}
+	if len(targets) == 0 {
+		targets = ToolNames
 	}
 
 	tools := make([]AITool, 0, len(targets))
 	for _, target := range targets {
 		tool, err := createTool(target)
 		if err != nil {
-			return fmt.Errorf("failed to create tool %s: %w", target, err)
+			return nil, fmt.Errorf("failed to create tool %s: %w", target, err)
 		}
 		tools = append(tools, tool)
 	}
+	return tools, nil
+}
 
-	if watch {
-		return watchAndBuild(config, tools)
-	}
-
-	return buildOnce(config, tools)
+// ImportOptions configures an import
+type ImportOptions struct {
+	// ToMdc writes the imported rules to .cursor/rules/imported.mdc, or to
+	// the configured rules directory
+	ToMdc bool
+	// ConfigPath is an explicit settings file to use instead of .syncai.yaml
+	ConfigPath string
+	// RulesDir overrides the rules directory ToMdc writes to
+	RulesDir string
+	// From is the tool to import from with ToMdc. When empty, the user is
+	// asked to choose if several tools are found and the terminal is
+	// interactive; otherwise the first found tool is used.
+	From string
 }
 
 // Import imports existing AI tool configurations
-func Import() error {
+func Import(opts ImportOptions) error {
 	wd, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("failed to get working directory: %w", err)
@@ -69,23 +854,32 @@ func Import() error {
 
 	fmt.Printf("Importing AI tool configurations from %s...\n", wd)
 
+	// Stop scanning on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Check what AI tools are already configured
-	tools := []string{"cursor", "windsurf", "roo-code", "cline", "claude-code"}
 	found := []string{}
+	imported := map[string]*ProjectConfig{}
 	
-	for _, toolName := range tools {
+	for _, toolName := range ToolNames {
 		tool, err := createTool(toolName)
 		if err != nil {
 			continue
 		}
 		
-		config, err := tool.Import(wd)
+		config, err := tool.Import(ctx, wd)
+		if ctx.Err() != nil {
+			return fmt.Errorf("import canceled: %w", ctx.Err())
+		}
 		if err != nil {
 			continue
 		}
+		reportWarnings(os.Stderr, config.Warnings)
 		
 		if config.CursorRules != "" || len(config.MdcFiles) > 0 {
 			found = append(found, toolName)
+			imported[toolName] = config
 		}
 	}
 	
@@ -96,132 +890,797 @@ func Import() error {
 	
 	fmt.Printf("  ✓ Found configurations for: %s\n", strings.Join(found, ", "))
 	
-	// For now, we'll focus on importing from the first found tool
-	// In a real implementation, you might want to ask the user which one to import from
-	if len(found) > 0 {
-		fmt.Printf("  → Use 'syncai build' to generate configurations for other tools\n")
+	if opts.ToMdc {
+		source, err := chooseImportSource(found, opts.From)
+		if err != nil {
+			return err
+		}
+		if source == "" {
+			fmt.Printf("  ⚠ Only Cursor rules found, nothing to convert\n")
+			return nil
+		}
+		rulesDir, err := importRulesDir(wd, opts)
+		if err != nil {
+			return err
+		}
+		return importToMdc(wd, rulesDir, source, imported[source])
 	}
 	
+	fmt.Printf("  → Use 'syncai build' to generate configurations for other tools\n")
 	return nil
 }
 
-func loadProjectConfig() (*ProjectConfig, error) {
+// loadProjectConfig loads the rules of the project in the working directory,
+// using the ConfigPath, RulesDir and FollowSymlinks options. Problems that
+// don't prevent loading are returned as warnings.
+func loadProjectConfig(opts BuildOptions) (*ProjectConfig, []Warning, error) {
 	wd, err := os.Getwd()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get working directory: %w", err)
+		return nil, nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	settings, err := loadSettings(wd, opts.ConfigPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rulesDir := settings.rulesDir()
+	if opts.RulesDir != "" {
+		if rulesDir, err = cleanRulesDir(opts.RulesDir); err != nil {
+			return nil, nil, fmt.Errorf("--rules-dir: %w", err)
+		}
 	}
 
 	config := &ProjectConfig{
 		RootPath: wd,
+		Settings: settings,
+		RulesDir: rulesDir,
 	}
 
 	// Load .cursorrules file
-	cursorRulesPath := filepath.Join(wd, ".cursorrules")
-	if data, err := os.ReadFile(cursorRulesPath); err == nil {
-		config.CursorRules = string(data)
+	config.CursorRules = readCursorRules(wd)
+
+	// Find all rules directories, and the languages used in the project
+	rulesDirs, languages, warnings, err := findRulesDirs(wd, rulesDir, opts.FollowSymlinks)
+	if err != nil {
+		return nil, nil, err
+	}
+	config.RulesDirs = rulesDirs
+	config.Languages = languages
+
+	cache := newRuleCache(wd, opts)
+	mdcFiles, mdcWarnings, err := loadMdcFiles(wd, rulesDirs, rulesDir, settings.ruleExtensions(), cache)
+	if err != nil {
+		return nil, nil, err
 	}
+	config.MdcFiles = mdcFiles
+	cache.save()
+
+	return config, append(warnings, mdcWarnings...), nil
+}
 
-	// Find all .cursor directories
-	cursorDirs := []string{}
-	err = filepath.Walk(wd, func(path string, info os.FileInfo, err error) error {
+// findRulesDirs walks the tree at root for rules directories, returned in the
+// order of sortRulesDirs, and the languages of its source files
+func findRulesDirs(root, rulesDir string, followSymlinks bool) ([]string, map[string]bool, []Warning, error) {
+	rulesDirs := []string{}
+	languages := map[string]bool{}
+	warnings := []Warning{}
+	err := walkProject(root, followSymlinks, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			return err
+			return skipUnreadable(&warnings, path, info, err)
 		}
-		if info.IsDir() && info.Name() == ".cursor" {
-			cursorDirs = append(cursorDirs, path)
+		// Skip syncai's own cache, which may contain fetched rules, and the
+		// directories tools write to, so generated files are never read as rules
+		if info.IsDir() && (info.Name() == ".syncai" || isOutputDirName(info.Name())) {
+			return filepath.SkipDir
+		}
+		if info.IsDir() && isRulesDir(path, rulesDir) {
+			rulesDirs = append(rulesDirs, path)
+		}
+		if language := fileLanguage(path); language != "" && info.Mode().IsRegular() {
+			languages[language] = true
 		}
 		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to find .cursor directories: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to find %s directories: %w", rulesDir, err)
 	}
+	sortRulesDirs(rulesDirs)
+	return rulesDirs, languages, warnings, nil
+}
 
-	config.CursorDirs = cursorDirs
+// walkProject walks the tree at root like filepath.Walk. With followSymlinks,
+// symlinks to directories are walked too, after the rest of the tree. Paths
+// under a link are reported under the link, even when the project contains
+// its target, so that a .cursor link to a shared folder is found under its own
+// name. The link itself is reported with the target's FileInfo under its own
+// name. A link to a directory containing it, or to one of the links followed
+// to reach it, isn't entered, so symlink loops end.
+func walkProject(root string, followSymlinks bool, fn filepath.WalkFunc) error {
+	if !followSymlinks {
+		return filepath.Walk(root, fn)
+	}
 
-	// Load MDC files from all .cursor/rules directories
-	mdcFiles := []MdcFile{}
-	for _, cursorDir := range cursorDirs {
-		rulesDir := filepath.Join(cursorDir, "rules")
-		if _, err := os.Stat(rulesDir); os.IsNotExist(err) {
-			continue
-		}
+	// followed holds the real targets of the links leading to a walk
+	type link struct {
+		path, target string
+		followed     []string
+	}
+	pending := []link{{path: root, target: root}}
+	for len(pending) > 0 {
+		next := pending[0]
+		pending = pending[1:]
+		err := filepath.Walk(next.target, func(path string, info os.FileInfo, err error) error {
+			shown := filepath.Join(next.path, strings.TrimPrefix(path, next.target))
+			if err != nil {
+				return fn(shown, info, err)
+			}
+			if info.IsDir() {
+				if path == next.target && next.path != root {
+					// Already reported as the link leading here
+					return nil
+				}
+				return fn(shown, info, nil)
+			}
 
-		err = filepath.Walk(rulesDir, func(path string, info os.FileInfo, err error) error {
+			target, statErr := os.Stat(path)
+			if info.Mode()&os.ModeSymlink == 0 || statErr != nil || !target.IsDir() {
+				return fn(shown, info, nil)
+			}
+			real, err := filepath.EvalSymlinks(path)
 			if err != nil {
-				return err
+				return fn(shown, info, err)
 			}
-			if !info.IsDir() && strings.HasSuffix(path, ".mdc") {
-				mdcFile, err := parseMdcFile(path)
-				if err != nil {
-					log.Printf("Warning: failed to parse MDC file %s: %v", path, err)
+			if err := fn(shown, namedFileInfo{FileInfo: target, name: info.Name()}, nil); err != nil {
+				if err == filepath.SkipDir {
 					return nil
 				}
-				mdcFiles = append(mdcFiles, *mdcFile)
+				return err
+			}
+			parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+			if err != nil || containsPath(real, parent) || slices.ContainsFunc(next.followed, func(followed string) bool {
+				return containsPath(real, followed)
+			}) {
+				return nil
 			}
+			pending = append(pending, link{path: shown, target: real, followed: append(slices.Clone(next.followed), real)})
 			return nil
 		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to walk rules directory %s: %w", rulesDir, err)
+			return err
 		}
 	}
+	return nil
+}
 
-	config.MdcFiles = mdcFiles
+// containsPath reports whether path is dir or inside it
+func containsPath(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
 
-	return config, nil
+// namedFileInfo is the FileInfo of a symlink's target under the link's name
+type namedFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i namedFileInfo) Name() string {
+	return i.name
+}
+
+// sortRulesDirs orders rules directories by depth, shallowest first, then
+// alphabetically, so the rules of a parent folder come before the more
+// specific rules of its subfolders
+func sortRulesDirs(rulesDirs []string) {
+	sort.SliceStable(rulesDirs, func(i, j int) bool {
+		depthI := strings.Count(rulesDirs[i], string(filepath.Separator))
+		depthJ := strings.Count(rulesDirs[j], string(filepath.Separator))
+		if depthI != depthJ {
+			return depthI < depthJ
+		}
+		return rulesDirs[i] < rulesDirs[j]
+	})
+}
+
+// isRulesDir reports whether dir is a rules directory, whose path ends with
+// the relative rulesDir path, such as .cursor/rules
+func isRulesDir(dir, rulesDir string) bool {
+	return strings.HasSuffix(dir, string(filepath.Separator)+filepath.FromSlash(rulesDir))
+}
+
+// rulesBaseDir returns the folder the rules in a rules directory apply to
+func rulesBaseDir(dir, rulesDir string) string {
+	return strings.TrimSuffix(dir, string(filepath.Separator)+filepath.FromSlash(rulesDir))
+}
+
+// cleanRulesDir checks a configured rules directory is a relative path inside
+// the project, returning it cleaned and with forward slashes
+func cleanRulesDir(dir string) (string, error) {
+	cleaned := path.Clean(filepath.ToSlash(dir))
+	if path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("rules directory %q must be a relative path inside the project", dir)
+	}
+	return cleaned, nil
+}
+
+// rulesDir returns the relative path of rules directories
+func (c *ProjectConfig) rulesDir() string {
+	if c.RulesDir == "" {
+		return DefaultRulesDir
+	}
+	return c.RulesDir
+}
+
+// inRulesDir reports whether path is inside one of the project's rules directories
+func (c *ProjectConfig) inRulesDir(path string) bool {
+	for _, rulesDir := range c.RulesDirs {
+		if strings.HasPrefix(path, rulesDir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipUnreadable lets a walk continue past entries it isn't allowed to read,
+// adding a warning instead of failing. Other errors are returned as is.
+func skipUnreadable(warnings *[]Warning, path string, info os.FileInfo, err error) error {
+	if info == nil || !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	*warnings = append(*warnings, Warning{Path: path, Message: fmt.Sprintf("skipped unreadable entry: %v", err), Severity: SeverityError})
+	if info.IsDir() {
+		return filepath.SkipDir
+	}
+	return nil
+}
+
+// hasRuleExtension reports whether path has one of the rule file extensions
+func hasRuleExtension(path string, extensions []string) bool {
+	for _, extension := range extensions {
+		if strings.HasSuffix(path, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// readCursorRules reads the global rules of rootPath: the .cursorrules file,
+// followed by the .md fragments in .cursorrules.d in file name order
+func readCursorRules(rootPath string) string {
+	parts := []string{}
+	if data, err := os.ReadFile(filepath.Join(rootPath, ".cursorrules")); err == nil && len(data) > 0 {
+		parts = append(parts, string(data))
+	}
+
+	fragments, _ := filepath.Glob(filepath.Join(rootPath, ".cursorrules.d", "*.md"))
+	sort.Strings(fragments)
+	for _, fragment := range fragments {
+		if data, err := os.ReadFile(fragment); err == nil && len(data) > 0 {
+			parts = append(parts, string(data))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// isCursorRulesPath reports whether path is .cursorrules or one of its fragments
+func (c *ProjectConfig) isCursorRulesPath(path string) bool {
+	if path == filepath.Join(c.RootPath, ".cursorrules") {
+		return true
+	}
+	return filepath.Dir(path) == filepath.Join(c.RootPath, ".cursorrules.d") && strings.HasSuffix(path, ".md")
+}
+
+// parseWorkers is the number of rule files loadMdcFiles parses at once, or 0
+// for GOMAXPROCS. Benchmarks set it to compare serial and parallel loading.
+var parseWorkers = 0
+
+// loadMdcFiles loads the rule files with one of the given extensions from
+// each of rulesDirs, whose rules apply to the folder containing the relative
+// rulesDir path. Files that can't be parsed are skipped with a warning.
+func loadMdcFiles(rootPath string, rulesDirs []string, rulesDir string, extensions []string, cache *ruleCache) ([]MdcFile, []Warning, error) {
+	type ruleFile struct {
+		path    string
+		baseDir string
+		info    os.FileInfo
+	}
+
+	// Find the rule files first, so they can be parsed in parallel
+	files := []ruleFile{}
+	warnings := []Warning{}
+	for _, dir := range rulesDirs {
+		baseDir := rulesBaseDir(dir, rulesDir)
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return skipUnreadable(&warnings, path, info, err)
+			}
+			if !info.IsDir() && hasRuleExtension(path, extensions) {
+				files = append(files, ruleFile{path: path, baseDir: baseDir, info: info})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to walk rules directory %s: %w", dir, err)
+		}
+	}
+
+	// Indexed by walk position so the rules and warnings keep a stable order
+	type parseResult struct {
+		mdcFile  *MdcFile
+		warnings []Warning
+	}
+	results := make([]parseResult, len(files))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	workers := parseWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	for w := 0; w < min(workers, len(files)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				file := files[i]
+				mdcFile, parseWarnings, err := cache.parse(rootPath, file.path, file.info)
+				if err != nil {
+					results[i].warnings = []Warning{{Path: file.path, Message: fmt.Sprintf("skipped rule that failed to parse: %v", err), Severity: SeverityError}}
+					continue
+				}
+				if isEmptyRule(mdcFile) {
+					// Tools would emit a heading with nothing under it
+					results[i].warnings = append(parseWarnings, Warning{Path: file.path, Message: "skipped empty rule", Severity: SeverityWarning})
+					continue
+				}
+				mdcFile.RootGlobs = rootRelativeGlobs(rootPath, file.baseDir, mdcFile.Globs)
+				mdcFile.BaseDir = file.baseDir
+				mdcFile.External = isOutsideRoot(rootPath, file.path)
+				results[i] = parseResult{mdcFile: mdcFile, warnings: parseWarnings}
+			}
+		}()
+	}
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	mdcFiles := []MdcFile{}
+	for _, result := range results {
+		warnings = append(warnings, result.warnings...)
+		if result.mdcFile != nil {
+			mdcFiles = append(mdcFiles, *result.mdcFile)
+		}
+	}
+
+	return mdcFiles, warnings, nil
 }
 
-func parseMdcFile(path string) (*MdcFile, error) {
+// isEmptyRule reports whether a rule has no content to emit, such as a blank
+// file or one with only frontmatter
+func isEmptyRule(mdcFile *MdcFile) bool {
+	return strings.TrimSpace(mdcFile.Content) == "" && len(mdcFile.Examples) == 0 && len(mdcFile.References) == 0
+}
+
+// applyChange updates the loaded rules after a watched file changed. Modified
+// files are re-parsed individually; created, removed or renamed entries cause
+// the known rules directories to be re-read, without walking the whole tree.
+func (c *ProjectConfig) applyChange(event fsnotify.Event) error {
+	if c.isCursorRulesPath(event.Name) {
+		c.CursorRules = c.included.mergeCursorRules(readCursorRules(c.RootPath))
+		return nil
+	}
+
+	if event.Op&fsnotify.Write == fsnotify.Write && hasRuleExtension(event.Name, c.Settings.ruleExtensions()) {
+		for i, mdcFile := range c.MdcFiles {
+			if mdcFile.Path != event.Name {
+				continue
+			}
+			cache := newRuleCache(c.RootPath, c.Options)
+			updated, warnings, err := cache.parse(c.RootPath, event.Name, nil)
+			cache.save()
+			if err != nil {
+				return fmt.Errorf("failed to parse MDC file %s: %w", event.Name, err)
+			}
+			if isEmptyRule(updated) {
+				// Reload the rules directories, which skips it with a warning
+				break
+			}
+			reportWarnings(c.warningOutput(), warnings)
+			updated.RootGlobs = rootRelativeGlobs(c.RootPath, mdcFile.BaseDir, updated.Globs)
+			updated.BaseDir = mdcFile.BaseDir
+			updated.External = mdcFile.External
+			c.MdcFiles[i] = *updated
+			reportWarnings(c.warningOutput(), c.filterMdcFiles())
+			return nil
+		}
+	}
+
+	return c.reloadRules()
+}
+
+// reloadRules reads the rules of the known rules directories again
+func (c *ProjectConfig) reloadRules() error {
+	cache := newRuleCache(c.RootPath, c.Options)
+	mdcFiles, warnings, err := loadMdcFiles(c.RootPath, c.RulesDirs, c.rulesDir(), c.Settings.ruleExtensions(), cache)
+	if err != nil {
+		return err
+	}
+	cache.save()
+	reportWarnings(c.warningOutput(), warnings)
+	c.MdcFiles = c.included.mergeMdcFiles(mdcFiles, c.rulesDir())
+	reportWarnings(c.warningOutput(), c.filterMdcFiles())
+	return nil
+}
+
+// parseMdcFile parses a rule file. Frontmatter problems that don't prevent
+// parsing are returned as warnings. Content included with contentFrom must be
+// inside rootPath.
+func parseMdcFile(rootPath, path string) (*MdcFile, []Warning, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
 	}
+	return parseMdcData(rootPath, path, data)
+}
+
+// parseMdcData parses the data of the rule file at path
+func parseMdcData(rootPath, path string, data []byte) (*MdcFile, []Warning, error) {
+	warnings := []Warning{}
 
-	content := string(data)
+	content := strings.TrimPrefix(string(data), "\ufeff")
 	lines := strings.Split(content, "\n")
 
 	mdcFile := &MdcFile{
-		Path:    path,
-		Content: content,
+		Path:        path,
+		Content:     content,
+		frontmatter: map[string]string{},
 	}
 
-	// Parse frontmatter-like metadata
-	inFrontmatter := false
-	contentStart := 0
-	for i, line := range lines {
+	// Parse frontmatter-like metadata. Frontmatter must open on the first line,
+	// so a --- horizontal rule in the content isn't mistaken for it.
+	end, unclosed := frontmatterEnd(lines)
+	if unclosed {
+		warnings = append(warnings, Warning{Path: path, Message: "frontmatter is not closed with ---, reading the whole file as content", Severity: SeverityWarning})
+	}
+	contentFrom := ""
+	// blockList is the list field that a block-style YAML list continues
+	var blockList *[]string
+	// Notes of the globs, and whether the last glob is a block-style entry
+	// whose note: line may follow
+	globNotes := []string{}
+	notePending := false
+	for _, line := range lines[1:max(end, 1)] {
 		line = strings.TrimSpace(line)
-		if line == "---" {
-			if !inFrontmatter {
-				inFrontmatter = true
-				continue
-			} else {
-				contentStart = i + 1
-				break
-			}
+		if notePending && strings.HasPrefix(line, "note:") {
+			globNotes[len(globNotes)-1] = unquote(strings.TrimPrefix(line, "note:"))
+			notePending = false
+			continue
 		}
-		if inFrontmatter {
-			if strings.HasPrefix(line, "description:") {
-				mdcFile.Description = strings.TrimSpace(strings.TrimPrefix(line, "description:"))
-			} else if strings.HasPrefix(line, "alwaysApply:") {
-				mdcFile.AlwaysApply = strings.TrimSpace(strings.TrimPrefix(line, "alwaysApply:")) == "true"
-			} else if strings.HasPrefix(line, "globs:") {
-				globsStr := strings.TrimSpace(strings.TrimPrefix(line, "globs:"))
-				if strings.HasPrefix(globsStr, "[") && strings.HasSuffix(globsStr, "]") {
-					globsStr = strings.Trim(globsStr, "[]")
-					globs := strings.Split(globsStr, ",")
-					for i, glob := range globs {
-						globs[i] = strings.Trim(strings.TrimSpace(glob), "\"'")
+		notePending = false
+		if key, value, ok := strings.Cut(line, ":"); ok && !strings.HasPrefix(line, "- ") && !strings.ContainsAny(key, " \t") {
+			mdcFile.frontmatter[key] = strings.TrimSpace(value)
+		}
+		if strings.HasPrefix(line, "name:") {
+			mdcFile.Name = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "name:")), "\"'")
+		} else if strings.HasPrefix(line, "description:") {
+			mdcFile.Description = strings.TrimSpace(strings.TrimPrefix(line, "description:"))
+		} else if strings.HasPrefix(line, "scope:") {
+			mdcFile.Scope = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "scope:")), "\"'")
+			switch mdcFile.Scope {
+			case ScopeGlobal, ScopeFolder, ScopeConditional:
+			default:
+				warnings = append(warnings, Warning{Path: path, Message: fmt.Sprintf("unknown scope %q, treating it as %s", mdcFile.Scope, ScopeConditional), Severity: SeverityWarning})
+				mdcFile.Scope = ScopeConditional
+			}
+		} else if strings.HasPrefix(line, "position:") {
+			mdcFile.Position = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "position:")), "\"'")
+			switch mdcFile.Position {
+			case PositionBefore, PositionAfter:
+			default:
+				warnings = append(warnings, Warning{Path: path, Message: fmt.Sprintf("unknown position %q, treating it as %s", mdcFile.Position, PositionAfter), Severity: SeverityWarning})
+				mdcFile.Position = PositionAfter
+			}
+		} else if strings.HasPrefix(line, "level:") {
+			mdcFile.Level = strings.ToLower(strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "level:")), "\"'"))
+			switch mdcFile.Level {
+			case LevelMust, LevelShould, LevelMay:
+			default:
+				warnings = append(warnings, Warning{Path: path, Message: fmt.Sprintf("unknown level %q, ignoring it", mdcFile.Level), Severity: SeverityWarning})
+				mdcFile.Level = ""
+			}
+		} else if strings.HasPrefix(line, "contentFrom:") {
+			contentFrom = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "contentFrom:")), "\"'")
+		} else if strings.HasPrefix(line, "content:") {
+			value := strings.TrimSpace(strings.TrimPrefix(line, "content:"))
+			if strings.HasPrefix(value, "!include ") {
+				contentFrom = strings.Trim(strings.TrimSpace(strings.TrimPrefix(value, "!include ")), "\"'")
+			}
+		} else if strings.HasPrefix(line, "alwaysApply:") {
+			value := strings.TrimSpace(strings.TrimPrefix(line, "alwaysApply:"))
+			alwaysApply, ok := parseFlag(value)
+			if !ok {
+				warnings = append(warnings, Warning{Path: path, Message: fmt.Sprintf("unknown alwaysApply value %s, treating it as false", value), Severity: SeverityWarning})
+			}
+			mdcFile.AlwaysApply = alwaysApply
+		} else if strings.HasPrefix(line, "globs:") {
+			mdcFile.Globs, globNotes = parseGlobs(strings.TrimSpace(strings.TrimPrefix(line, "globs:")))
+			blockList = emptyList(&mdcFile.Globs)
+			continue
+		} else if strings.HasPrefix(line, "examples:") {
+			mdcFile.Examples = parseList(strings.TrimSpace(strings.TrimPrefix(line, "examples:")))
+			blockList = emptyList(&mdcFile.Examples)
+			continue
+		} else if strings.HasPrefix(line, "references:") {
+			mdcFile.References = parseList(strings.TrimSpace(strings.TrimPrefix(line, "references:")))
+			blockList = emptyList(&mdcFile.References)
+			continue
+		} else if strings.HasPrefix(line, "languages:") {
+			mdcFile.Languages = parseList(strings.TrimSpace(strings.TrimPrefix(line, "languages:")))
+			blockList = emptyList(&mdcFile.Languages)
+			continue
+		} else if blockList != nil && strings.HasPrefix(line, "- ") {
+			// Block-style YAML list following an empty list field
+			item := strings.TrimPrefix(line, "- ")
+			if blockList == &mdcFile.Globs {
+				if pattern, ok := strings.CutPrefix(item, "pattern:"); ok {
+					// The first line of an entry written as a block mapping
+					if pattern = unquote(pattern); pattern != "" {
+						mdcFile.Globs = append(mdcFile.Globs, pattern)
+						globNotes = append(globNotes, "")
+						notePending = true
 					}
-					mdcFile.Globs = globs
+					continue
 				}
+				globs, notes := parseGlobs(item)
+				*blockList = append(*blockList, globs...)
+				globNotes = append(globNotes, notes...)
+			} else {
+				*blockList = append(*blockList, parseList(item)...)
+			}
+			continue
+		}
+		blockList = nil
+	}
+
+	for _, note := range globNotes {
+		if note != "" {
+			mdcFile.GlobNotes = globNotes
+			break
+		}
+	}
+
+	if end > 0 {
+		mdcFile.Content = strings.Join(lines[end+1:], "\n")
+	}
+
+	if contentFrom != "" {
+		included, err := readIncludedContent(rootPath, path, contentFrom)
+		if err != nil {
+			return nil, nil, err
+		}
+		if strings.TrimSpace(mdcFile.Content) != "" {
+			warnings = append(warnings, Warning{Path: path, Message: fmt.Sprintf("content is replaced by %s", contentFrom), Severity: SeverityWarning})
+		}
+		mdcFile.Content = included
+		mdcFile.contentFrom = contentFrom
+	}
+
+	for _, language := range mdcFile.Languages {
+		if !isKnownLanguage(language) {
+			warnings = append(warnings, Warning{Path: path, Message: fmt.Sprintf("unknown language %q in languages, expected one of %s", language, strings.Join(knownLanguages(), ", ")), Severity: SeverityWarning})
+		}
+	}
+
+	if mdcFile.Name == "" {
+		mdcFile.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	return mdcFile, warnings, nil
+}
+
+// parseFlag reads a boolean frontmatter value the way people write it by hand:
+// true/false, yes/no or 1/0 in any case, optionally quoted. ok is false for
+// anything else.
+func parseFlag(value string) (flag bool, ok bool) {
+	switch strings.ToLower(strings.Trim(value, "\"'")) {
+	case "true", "yes", "1":
+		return true, true
+	case "false", "no", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// frontmatterEnd returns the index of the line closing the frontmatter that
+// opens on the first line, or -1 if the lines don't start with frontmatter.
+// Both delimiters must be --- on a line of their own, and the lines between
+// must read as YAML, so content opening with a --- horizontal rule isn't
+// mistaken for frontmatter even when another --- follows. unclosed reports
+// frontmatter that runs to the end of the file without a closing ---.
+func frontmatterEnd(lines []string) (end int, unclosed bool) {
+	if len(lines) == 0 || !isFrontmatterDelimiter(lines[0]) {
+		return -1, false
+	}
+	for i := 1; i < len(lines); i++ {
+		if isFrontmatterDelimiter(lines[i]) {
+			return i, false
+		}
+		if !isFrontmatterLine(lines[i]) {
+			return -1, false
+		}
+	}
+	return -1, true
+}
+
+// isFrontmatterDelimiter reports whether line is --- at the start of a line,
+// followed by nothing but whitespace
+func isFrontmatterDelimiter(line string) bool {
+	return strings.TrimRight(line, " \t\r") == "---"
+}
+
+// isFrontmatterLine reports whether line can appear in frontmatter: blank, a
+// comment, a key: value field, a list item or an indented continuation
+func isFrontmatterLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || trimmed == "-" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "- ") {
+		return true
+	}
+	if line[0] == ' ' || line[0] == '\t' {
+		return true
+	}
+	key, _, ok := strings.Cut(trimmed, ":")
+	return ok && key != "" && !strings.ContainsAny(key, " \t")
+}
+
+// readIncludedContent reads the file a rule includes as its content. The
+// reference is relative to the rule file and must stay inside rootPath.
+func readIncludedContent(rootPath, rulePath, ref string) (string, error) {
+	includePath := ref
+	if !filepath.IsAbs(includePath) {
+		includePath = filepath.Join(filepath.Dir(rulePath), ref)
+	}
+	rel, err := filepath.Rel(rootPath, includePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("included content %s is outside the project root", ref)
+	}
+
+	data, err := os.ReadFile(includePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to include content %s: %w", ref, err)
+	}
+	return string(data), nil
+}
+
+// parseList parses the value of a list field, which can be a bracketed list or
+// a single (optionally quoted) item. Unlike globs, unbracketed values are never
+// split on commas.
+func parseList(value string) []string {
+	items := []string{value}
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		items = splitGlobList(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]"))
+	}
+
+	list := []string{}
+	for _, item := range items {
+		item = strings.Trim(strings.TrimSpace(item), "\"'")
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// emptyList returns list if it has no items yet, so that a block-style list
+// may follow, or nil otherwise
+func emptyList(list *[]string) *[]string {
+	if len(*list) == 0 {
+		return list
+	}
+	return nil
+}
+
+// parseGlobs parses the value of a globs field, which can be a bracketed list,
+// a single (optionally quoted) pattern or a comma-separated list of patterns,
+// such as the unquoted *.ts,*.tsx Cursor writes. Each pattern may also be written as {pattern: "src/**", note: "app code"};
+// the note of each pattern, or an empty string, is returned alongside it.
+func parseGlobs(value string) (globs []string, notes []string) {
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	}
+
+	globs, notes = []string{}, []string{}
+	for _, glob := range splitGlobList(value) {
+		glob = strings.TrimSpace(glob)
+		note := ""
+		if pattern, patternNote, ok := parseGlobObject(glob); ok {
+			glob, note = pattern, patternNote
+		}
+		glob = strings.Trim(glob, "\"'")
+		if glob != "" {
+			globs = append(globs, glob)
+			notes = append(notes, note)
+		}
+	}
+	return globs, notes
+}
+
+// parseGlobObject parses a globs entry written as a flow mapping with a
+// pattern and an optional note. ok is false for anything else, including
+// brace expansions such as {src,lib}/**.
+func parseGlobObject(value string) (pattern, note string, ok bool) {
+	if !strings.HasPrefix(value, "{") || !strings.HasSuffix(value, "}") {
+		return "", "", false
+	}
+	for _, field := range splitGlobList(value[1 : len(value)-1]) {
+		key, fieldValue, found := strings.Cut(field, ":")
+		if !found {
+			return "", "", false
+		}
+		switch strings.TrimSpace(key) {
+		case "pattern":
+			pattern = unquote(fieldValue)
+		case "note":
+			note = unquote(fieldValue)
+		default:
+			return "", "", false
+		}
+	}
+	return pattern, note, pattern != ""
+}
+
+// unquote trims a frontmatter value and the quotes around it
+func unquote(value string) string {
+	return strings.Trim(strings.TrimSpace(value), "\"'")
+}
+
+// splitGlobList splits a comma-separated list of globs, ignoring commas inside
+// quotes, brace expansions such as "*.{ts,tsx}" and parentheses, such as
+// those around the note of a glob in combined outputs
+func splitGlobList(value string) []string {
+	parts := []string{}
+	depth := 0
+	var quote rune
+	start := 0
+	for i, r := range value {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
 			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '{' || r == '(':
+			depth++
+		case (r == '}' || r == ')') && depth > 0:
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, value[start:i])
+			start = i + 1
 		}
 	}
+	return append(parts, value[start:])
+}
 
-	if contentStart > 0 {
-		mdcFile.Content = strings.Join(lines[contentStart:], "\n")
+// rootRelativeGlobs prefixes the globs of a rule with baseDir, the folder
+// containing its rules directory, as they are written relative to that folder
+func rootRelativeGlobs(rootPath, baseDir string, globs []string) []string {
+	if len(globs) == 0 {
+		return nil
 	}
 
-	return mdcFile, nil
+	folder, err := filepath.Rel(rootPath, baseDir)
+	if err != nil || folder == "." {
+		return globs
+	}
+	folder = filepath.ToSlash(folder)
+
+	rootGlobs := make([]string, len(globs))
+	for i, glob := range globs {
+		glob = strings.TrimPrefix(strings.TrimPrefix(glob, "./"), "/")
+		rootGlobs[i] = path.Join(folder, glob)
+	}
+	return rootGlobs
 }
 
 func createTool(name string) (AITool, error) {
@@ -236,101 +1695,101 @@ func createTool(name string) (AITool, error) {
 		return &Cline{}, nil
 	case "claude-code":
 		return &ClaudeCode{}, nil
+	case "agents":
+		return &Agents{}, nil
 	default:
 		return nil, fmt.Errorf("unknown tool: %s", name)
 	}
 }
 
-func buildOnce(config *ProjectConfig, tools []AITool) error {
-	var wg sync.WaitGroup
-	errors := make(chan error, len(tools))
+// maxRulesExamples is how many of the rules over the MaxRules limit are listed
+const maxRulesExamples = 5
 
-	for _, tool := range tools {
-		wg.Add(1)
-		go func(t AITool) {
-			defer wg.Done()
-			if err := t.Build(config); err != nil {
-				errors <- fmt.Errorf("failed to build %s: %w", t.Name(), err)
-			}
-		}(tool)
+// checkMaxRules returns an InvalidError when more MDC rules were found than
+// the MaxRules option allows, listing the first rules over the limit. Rules
+// are ordered shallowest folder first, so these are usually the ones from an
+// unexpected nested or vendored directory.
+func (c *ProjectConfig) checkMaxRules() error {
+	maxRules := c.Options.MaxRules
+	if maxRules <= 0 || len(c.MdcFiles) <= maxRules {
+		return nil
 	}
 
-	wg.Wait()
-	close(errors)
-
-	for err := range errors {
+	var message strings.Builder
+	fmt.Fprintf(&message, "found %d MDC rules, more than --max-rules %d; the first rules over the limit are:", len(c.MdcFiles), maxRules)
+	extra := c.MdcFiles[maxRules:]
+	for _, mdcFile := range extra[:min(len(extra), maxRulesExamples)] {
+		relPath, err := filepath.Rel(c.RootPath, mdcFile.Path)
 		if err != nil {
-			return err
+			relPath = mdcFile.Path
 		}
+		fmt.Fprintf(&message, "\n  %s", filepath.ToSlash(relPath))
 	}
-
-	return nil
+	if len(extra) > maxRulesExamples {
+		fmt.Fprintf(&message, "\n  ...and %d more", len(extra)-maxRulesExamples)
+	}
+	return &InvalidError{Err: errors.New(message.String())}
 }
 
-func watchAndBuild(config *ProjectConfig, tools []AITool) error {
-	watcher, err := fsnotify.NewWatcher()
+func buildOnce(config *ProjectConfig, tools []AITool) error {
+	if err := config.checkMaxRules(); err != nil {
+		return err
+	}
+	config, err := config.applyTransformCmd()
 	if err != nil {
-		return fmt.Errorf("failed to create file watcher: %w", err)
+		return err
 	}
-	defer watcher.Close()
-
-	// Add files to watch
-	cursorRulesPath := filepath.Join(config.RootPath, ".cursorrules")
-	if _, err := os.Stat(cursorRulesPath); err == nil {
-		err = watcher.Add(cursorRulesPath)
+	if config.Options.FileLists {
+		files, warnings, err := loadProjectFiles(config.RootPath)
 		if err != nil {
-			return fmt.Errorf("failed to watch .cursorrules: %w", err)
+			return err
 		}
+		reportWarnings(config.warningOutput(), warnings)
+		listed := *config
+		listed.files = files
+		config = &listed
 	}
 
-	for _, cursorDir := range config.CursorDirs {
-		rulesDir := filepath.Join(cursorDir, "rules")
-		if _, err := os.Stat(rulesDir); err == nil {
-			err = watcher.Add(rulesDir)
-			if err != nil {
-				return fmt.Errorf("failed to watch rules directory %s: %w", rulesDir, err)
-			}
-		}
-	}
+	var wg sync.WaitGroup
+	// Indexed by target position so failures are reported in a stable order
+	results := make([]error, len(tools))
+	// Structured logs are written a record at a time already; text progress
+	// is buffered per tool so concurrent builds don't interleave their lines
+	_, structured := config.Output.(*logWriter)
+	reporter := newProgressReporter(config.output(), len(tools))
 
-	// Initial build
-	if err := buildOnce(config, tools); err != nil {
-		return fmt.Errorf("initial build failed: %w", err)
+	for i, tool := range tools {
+		wg.Add(1)
+		go func(i int, t AITool) {
+			defer wg.Done()
+			toolConfig := config.forTool(t.Name())
+			if !structured {
+				progress := reporter.forTool(i)
+				defer progress.flush()
+				buffered := *toolConfig
+				buffered.Output = progress
+				toolConfig = &buffered
+			}
+			start := time.Now()
+			results[i] = t.Build(toolConfig)
+			if logger, ok := config.Output.(*logWriter); ok {
+				logger.logBuild(t.Name(), time.Since(start), results[i])
+			}
+		}(i, tool)
 	}
 
-	fmt.Println("Watching for changes... Press Ctrl+C to stop.")
+	wg.Wait()
 
-	// Watch for changes
-	for {
-		select {
-		case event, ok := <-watcher.Events:
-			if !ok {
-				return nil
-			}
-			if event.Op&fsnotify.Write == fsnotify.Write {
-				fmt.Printf("File modified: %s\n", event.Name)
-				
-				// Debounce: wait a bit for multiple rapid changes
-				time.Sleep(100 * time.Millisecond)
-				
-				// Reload config and rebuild
-				newConfig, err := loadProjectConfig()
-				if err != nil {
-					log.Printf("Failed to reload config: %v", err)
-					continue
-				}
-				
-				if err := buildOnce(newConfig, tools); err != nil {
-					log.Printf("Build failed: %v", err)
-				} else {
-					fmt.Println("Build completed successfully")
-				}
-			}
-		case err, ok := <-watcher.Errors:
-			if !ok {
-				return nil
-			}
-			log.Printf("Watcher error: %v", err)
+	buildErr := &BuildError{}
+	for i, err := range results {
+		if err != nil {
+			buildErr.Errors = append(buildErr.Errors, &ToolError{Tool: tools[i].Name(), Err: err})
 		}
 	}
+
+	if len(buildErr.Errors) > 0 {
+		return buildErr
+	}
+
+	return nil
 }
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Validate checks the rules of the project for problems and prints each one.
+// It returns an error when any problem is found.
+func Validate(opts BuildOptions) error {
+	opts.readOnly = true
+	config, err := loadBuildConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	reportWarnings(config.output(), config.Warnings)
+
+	problems, err := validateRules(config)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		fmt.Fprintf(config.output(), "✓ No problems found\n")
+		return nil
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(config.output(), "  ✗ %s\n", problem)
+	}
+	return &InvalidError{Err: fmt.Errorf("found %d problem(s)", len(problems))}
+}
+
+// validateRules returns a description of each problem found in the loaded
+// rules, including violations of the project's rule schema
+func validateRules(config *ProjectConfig) ([]string, error) {
+	problems := duplicateRuleNames(config)
+
+	schema, err := loadRuleSchema(config.RootPath)
+	if err != nil {
+		return nil, err
+	}
+	if schema != nil {
+		problems = append(problems, schemaViolations(config, schema)...)
+	}
+	return problems, nil
+}
+
+// duplicateRuleNames reports rules that share the same name, which makes the
+// generated file names collide and references to the rule ambiguous
+func duplicateRuleNames(config *ProjectConfig) []string {
+	byName := map[string][]string{}
+	names := []string{}
+	for _, mdcFile := range config.MdcFiles {
+		if _, ok := byName[mdcFile.Name]; !ok {
+			names = append(names, mdcFile.Name)
+		}
+		path := mdcFile.Path
+		if rel, err := filepath.Rel(config.RootPath, path); err == nil {
+			path = rel
+		}
+		byName[mdcFile.Name] = append(byName[mdcFile.Name], path)
+	}
+
+	problems := []string{}
+	for _, name := range names {
+		if paths := byName[name]; len(paths) > 1 {
+			problems = append(problems, fmt.Sprintf("rule name %s is used by %s", name, strings.Join(paths, " and ")))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestDuplicateRuleNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name: "same name field",
+			files: map[string]string{
+				".cursor/rules/a.mdc": "---\nname: react\ndescription: A\n---\nUse hooks.\n",
+				".cursor/rules/b.mdc": "---\nname: react\ndescription: B\n---\nUse JSX.\n",
+			},
+			want: []string{"rule name react is used by .cursor/rules/a.mdc and .cursor/rules/b.mdc"},
+		},
+		{
+			name: "same file name in two rules directories",
+			files: map[string]string{
+				".cursor/rules/react.mdc":     "---\ndescription: A\n---\nUse hooks.\n",
+				"web/.cursor/rules/react.mdc": "---\ndescription: B\n---\nUse JSX.\n",
+			},
+			want: []string{"rule name react is used by .cursor/rules/react.mdc and web/.cursor/rules/react.mdc"},
+		},
+		{
+			name: "different names",
+			files: map[string]string{
+				".cursor/rules/a.mdc": "---\nname: react\ndescription: A\n---\nUse hooks.\n",
+				".cursor/rules/b.mdc": "---\nname: vue\ndescription: B\n---\nUse SFCs.\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+			t.Chdir(root)
+			config, err := loadBuildConfig(BuildOptions{NoCache: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			problems, err := validateRules(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(problems) != len(tt.want) {
+				t.Fatalf("got problems %q, want %q", problems, tt.want)
+			}
+			for i, want := range tt.want {
+				if problems[i] != filepath.FromSlash(want) {
+					t.Errorf("got %q, want %q", problems[i], want)
+				}
+			}
+
+			err = Validate(BuildOptions{NoCache: true})
+			var invalid *InvalidError
+			if len(tt.want) > 0 && !errors.As(err, &invalid) {
+				t.Errorf("Validate returned %v, want an InvalidError", err)
+			}
+			if len(tt.want) == 0 && err != nil {
+				t.Errorf("Validate returned %v, want no error", err)
+			}
+		})
+	}
+}
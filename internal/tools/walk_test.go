@@ -0,0 +1,203 @@
+package tools
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestUnreadableDirIsSkipped(t *testing.T) {
+	root := t.TempDir()
+	writeRule(t, filepath.Join(root, ".cursor", "rules", "global.mdc"), "---\ndescription: Global\nalwaysApply: true\n---\nUse tabs.\n")
+	secret := filepath.Join(root, "secret")
+	if err := os.Mkdir(secret, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(secret, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(secret, 0755) })
+	if _, err := os.ReadDir(secret); err == nil {
+		t.Skip("permissions aren't enforced for this user")
+	}
+	t.Chdir(root)
+
+	config, warnings, err := loadProjectConfig(BuildOptions{NoCache: true})
+	if err != nil {
+		t.Fatalf("unreadable directory failed the load: %v", err)
+	}
+	if len(config.MdcFiles) != 1 {
+		t.Errorf("got %d rules, want 1", len(config.MdcFiles))
+	}
+	found := false
+	for _, warning := range warnings {
+		found = found || warning.Path == secret
+	}
+	if !found {
+		t.Errorf("no warning for %s in %v", secret, warnings)
+	}
+}
+
+func TestSkipUnreadable(t *testing.T) {
+	dir, err := os.Stat(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	denied := &fs.PathError{Op: "open", Path: "secret", Err: fs.ErrPermission}
+
+	warnings := []Warning{}
+	if err := skipUnreadable(&warnings, "secret", dir, denied); err != filepath.SkipDir {
+		t.Errorf("unreadable directory: got %v, want SkipDir", err)
+	}
+	if len(warnings) != 1 || warnings[0].Path != "secret" || warnings[0].Severity != SeverityError {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	other := fmt.Errorf("disk failure")
+	if err := skipUnreadable(&warnings, "broken", dir, other); err != other {
+		t.Errorf("other error: got %v, want it returned as is", err)
+	}
+	if err := skipUnreadable(&warnings, "gone", nil, denied); err != denied {
+		t.Errorf("error without file info: got %v, want it returned as is", err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("got %d warnings, want 1", len(warnings))
+	}
+}
+
+// symlink creates a symbolic link at link pointing to target
+func symlink(t testing.TB, target, link string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks aren't supported: %v", err)
+	}
+}
+
+func TestWalkProjectFollowsSymlinks(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"pkg/a.txt": "a"})
+	// A link back to the root makes a loop, and a second link to pkg
+	// leads to a directory the walk also reaches at its real location
+	symlink(t, root, filepath.Join(root, "pkg", "loop"))
+	symlink(t, filepath.Join(root, "pkg"), filepath.Join(root, "alias"))
+
+	walk := func(followSymlinks bool) []string {
+		paths := []string{}
+		err := walkProject(root, followSymlinks, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(root, path)
+			paths = append(paths, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return paths
+	}
+
+	// Without following, links are reported as files
+	if got, want := walk(false), []string{".", "alias", "pkg", "pkg/a.txt", "pkg/loop"}; !slices.Equal(got, want) {
+		t.Errorf("without following symlinks got %v, want %v", got, want)
+	}
+	// Links are walked under their own name, and the loop ends
+	got := walk(true)
+	slices.Sort(got)
+	if want := []string{".", "alias", "alias/a.txt", "alias/loop", "pkg", "pkg/a.txt", "pkg/loop"}; !slices.Equal(got, want) {
+		t.Errorf("following symlinks got %v, want %v", got, want)
+	}
+}
+
+func TestSymlinkedExternalRules(t *testing.T) {
+	root := t.TempDir()
+	shared := t.TempDir()
+	writeFiles(t, shared, map[string]string{
+		"web/.cursor/rules/react.mdc": "---\ndescription: React\nglobs: \"*.tsx\"\n---\nUse hooks.\n",
+		"style.mdc":                   "---\ndescription: Style\nalwaysApply: true\n---\nUse tabs.\n",
+	})
+	symlink(t, filepath.Join(shared, "web"), filepath.Join(root, "web"))
+	symlink(t, filepath.Join(shared, "style.mdc"), filepath.Join(root, ".cursor", "rules", "style.mdc"))
+	t.Chdir(root)
+
+	tests := []struct {
+		name    string
+		options BuildOptions
+		want    []string
+		skipped int
+	}{
+		{name: "default", want: []string{}, skipped: 1},
+		{name: "follow symlinks", options: BuildOptions{FollowSymlinks: true}, want: []string{}, skipped: 2},
+		{name: "allow outside root", options: BuildOptions{AllowOutsideRoot: true}, want: []string{"style"}},
+		{name: "both", options: BuildOptions{FollowSymlinks: true, AllowOutsideRoot: true}, want: []string{"style", "react"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.options.NoCache = true
+			config, err := loadBuildConfig(tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, mdcFile := range config.MdcFiles {
+				names = append(names, mdcFile.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("got rules %v, want %v", names, tt.want)
+			}
+			skipped := 0
+			for _, warning := range config.Warnings {
+				if strings.Contains(warning.Message, "outside the project root") {
+					skipped++
+				}
+			}
+			if skipped != tt.skipped {
+				t.Errorf("got %d rules skipped for being outside the root, want %d", skipped, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestSymlinkedCursorDir(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"shared/cursor/rules/react.mdc": "---\ndescription: React\nglobs: \"*.tsx\"\n---\nUse hooks.\n",
+	})
+	symlink(t, filepath.Join("..", "shared", "cursor"), filepath.Join(root, "web", ".cursor"))
+	t.Chdir(root)
+
+	config, err := loadBuildConfig(BuildOptions{NoCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.MdcFiles) != 0 {
+		t.Errorf("got %d rules without --follow-symlinks, want none", len(config.MdcFiles))
+	}
+
+	config, err = loadBuildConfig(BuildOptions{NoCache: true, FollowSymlinks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rulesDir := filepath.Join(root, "web", ".cursor", "rules")
+	if !slices.Equal(config.RulesDirs, []string{rulesDir}) {
+		t.Errorf("rules directories %v, want %s", config.RulesDirs, rulesDir)
+	}
+	if len(config.MdcFiles) != 1 {
+		t.Fatalf("got %d rules, want 1", len(config.MdcFiles))
+	}
+	// The rule belongs to the folder of the link, not of its target
+	rule := config.MdcFiles[0]
+	if rule.BaseDir != filepath.Join(root, "web") || !slices.Equal(rule.RootGlobs, []string{"web/*.tsx"}) {
+		t.Errorf("got base directory %s and globs %v, want web and web/*.tsx", rule.BaseDir, rule.RootGlobs)
+	}
+	if rule.External {
+		t.Error("rule inside the project is marked as external")
+	}
+}
@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+)
+
+// Severity tells how much a warning affects the loaded rules
+type Severity string
+
+const (
+	// SeverityInfo is a suggestion, such as a lint issue that doesn't affect
+	// how the rule is loaded
+	SeverityInfo Severity = "info"
+	// SeverityWarning means a rule was loaded, but maybe not as intended
+	SeverityWarning Severity = "warning"
+	// SeverityError means a rule or directory was skipped
+	SeverityError Severity = "error"
+)
+
+// Warning is a problem found while loading the rules that doesn't stop the build
+type Warning struct {
+	Path     string
+	Message  string
+	Severity Severity
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s: %s", w.Severity, w.Path, w.Message)
+}
+
+// reportWarnings prints each warning on its own line
+func reportWarnings(w io.Writer, warnings []Warning) {
+	if logger, ok := w.(*logWriter); ok {
+		logger.logWarnings(warnings)
+		return
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "⚠ %s\n", warning)
+	}
+}
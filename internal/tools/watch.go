@@ -0,0 +1,409 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long a Watcher waits after a change for further changes
+// before rebuilding
+const DefaultDebounce = 100 * time.Millisecond
+
+// Watcher rebuilds AI tool configurations whenever their rules change. Changes
+// arriving within the debounce interval of each other are coalesced into a
+// single rebuild.
+type Watcher struct {
+	config *ProjectConfig
+	tools  []AITool
+	// Debounce is how long to wait after a change before rebuilding
+	Debounce time.Duration
+	// MaxDebounce, if set, forces a rebuild once this long has passed since the
+	// first pending change, even while changes keep arriving
+	MaxDebounce time.Duration
+
+	watcher *fsnotify.Watcher
+	// gitignore holds the patterns of the root .gitignore, whose directories
+	// aren't watched for new rules directories
+	gitignore *cursorIgnore
+	// Hashes of the generated files, to tell syncai's own writes from external edits
+	outputs map[string][sha256.Size]byte
+	// Files planned by the last dry-run build, to report only what a change affects
+	planned map[string][]byte
+	// buildTools, if set, replaces building the tools to disk, as serve does
+	buildTools func() error
+}
+
+// NewWatcher creates a Watcher that builds tools from config
+func NewWatcher(config *ProjectConfig, tools []AITool) *Watcher {
+	return &Watcher{
+		config:   config,
+		tools:    tools,
+		Debounce: DefaultDebounce,
+	}
+}
+
+// Start builds the tools, then rebuilds them on every change until ctx is canceled
+func (w *Watcher) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+	w.watcher = watcher
+
+	if err := w.addSourcePaths(); err != nil {
+		return err
+	}
+
+	// Initial build
+	if err := w.build(); err != nil {
+		return fmt.Errorf("initial build failed: %w", err)
+	}
+	if w.config.Options.WatchAll {
+		if w.outputs, err = watchOutputs(watcher, w.config, w.tools); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w.config.output(), "Watching for changes... Press Ctrl+C to stop.")
+
+	// The timer only runs while a rebuild is pending
+	rebuild := time.NewTimer(w.Debounce)
+	rebuild.Stop()
+	defer rebuild.Stop()
+	// pendingSince is when the first change of the pending rebuild arrived
+	var pendingSince time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Don't drop changes still waiting for the debounce interval
+			if !pendingSince.IsZero() {
+				rebuild.Stop()
+				w.rebuild()
+			}
+			fmt.Fprintln(w.config.output(), "Stopped watching for changes.")
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if w.handleEvent(event) {
+				if pendingSince.IsZero() {
+					pendingSince = time.Now()
+				}
+				rebuild.Reset(w.debounceDelay(time.Since(pendingSince)))
+			}
+		case <-rebuild.C:
+			pendingSince = time.Time{}
+			w.rebuild()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.config.logf(slog.LevelError, "Watcher error: %v", err)
+		}
+	}
+}
+
+// debounceDelay returns how long to wait for further changes, given how long
+// the pending rebuild has been waiting already
+func (w *Watcher) debounceDelay(pending time.Duration) time.Duration {
+	if w.MaxDebounce <= 0 {
+		return w.Debounce
+	}
+	return max(min(w.Debounce, w.MaxDebounce-pending), 0)
+}
+
+// addSourcePaths watches .cursorrules, its fragments, the rules directories
+// and the other directories of the project, in which rules directories may be
+// created later
+func (w *Watcher) addSourcePaths() error {
+	gitignore, err := loadIgnoreFile(w.config.RootPath, ".gitignore")
+	if err != nil {
+		return err
+	}
+	w.gitignore = gitignore
+
+	cursorRulesPath := filepath.Join(w.config.RootPath, ".cursorrules")
+	if _, err := os.Stat(cursorRulesPath); err == nil {
+		if err := w.watcher.Add(cursorRulesPath); err != nil {
+			return fmt.Errorf("failed to watch .cursorrules: %w", err)
+		}
+	}
+	fragmentsDir := filepath.Join(w.config.RootPath, ".cursorrules.d")
+	if _, err := os.Stat(fragmentsDir); err == nil {
+		if err := w.watcher.Add(fragmentsDir); err != nil {
+			return fmt.Errorf("failed to watch .cursorrules.d: %w", err)
+		}
+	}
+
+	for _, rulesDir := range w.config.RulesDirs {
+		if err := w.watcher.Add(rulesDir); err != nil {
+			return fmt.Errorf("failed to watch rules directory %s: %w", rulesDir, err)
+		}
+	}
+	_, err = w.watchDirs(w.config.RootPath)
+	return err
+}
+
+// unwatchedDirNames are directories of dependencies and build output, often
+// too large to watch, that never hold the project's own rules
+var unwatchedDirNames = map[string]bool{
+	".git":         true,
+	".syncai":      true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// skipsDir reports whether the directory at path, named name, is left
+// unwatched: dependencies, tool outputs and directories ignored by the root
+// .gitignore. The directories of the rules directory path, such as .cursor,
+// are watched even when ignored, as rules are often kept out of git.
+func (w *Watcher) skipsDir(path, name string) bool {
+	if unwatchedDirNames[name] || isOutputDirName(name) {
+		return true
+	}
+	if slices.Contains(strings.Split(w.config.rulesDir(), "/"), name) {
+		return false
+	}
+	rel, err := filepath.Rel(w.config.RootPath, path)
+	if err != nil || rel == "." || w.gitignore == nil {
+		return false
+	}
+	return w.gitignore.matches(filepath.ToSlash(rel), true)
+}
+
+// watchDirs watches dir and the directories in it, except those skipsDir
+// leaves out, and returns the rules directories among them. A directory that
+// can't be watched only hides rules created in it later, so failures are
+// logged once with their count rather than per directory.
+func (w *Watcher) watchDirs(dir string) ([]string, error) {
+	rulesDirs := []string{}
+	warnings := []Warning{}
+	failed := 0
+	var firstErr error
+	err := walkProject(dir, w.config.Options.FollowSymlinks, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return skipUnreadable(&warnings, path, info, err)
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if w.skipsDir(path, info.Name()) {
+			return filepath.SkipDir
+		}
+		if err := w.watcher.Add(path); err != nil {
+			if failed == 0 {
+				firstErr = fmt.Errorf("%s: %w", path, err)
+			}
+			failed++
+		}
+		if isRulesDir(path, w.config.rulesDir()) {
+			rulesDirs = append(rulesDirs, path)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	if failed > 0 {
+		w.config.logf(slog.LevelWarn, "Warning: failed to watch %d directories for new rules, such as %v", failed, firstErr)
+	}
+	reportWarnings(w.config.warningOutput(), warnings)
+	return rulesDirs, nil
+}
+
+// updateRulesDirs finds the rules directories created or removed by event
+// without walking the whole project again: a created directory is walked for
+// new ones, and a removed or renamed directory drops those inside it. It
+// reports whether the loaded rules changed.
+func (w *Watcher) updateRulesDirs(event fsnotify.Event) (bool, error) {
+	if rel, err := filepath.Rel(w.config.RootPath, event.Name); err == nil {
+		for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+			if unwatchedDirNames[part] || isOutputDirName(part) {
+				return false, nil
+			}
+		}
+	}
+
+	known := map[string]bool{}
+	for _, rulesDir := range w.config.RulesDirs {
+		known[rulesDir] = true
+	}
+	rulesDirs := []string{}
+	changed := false
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		for _, rulesDir := range w.config.RulesDirs {
+			if rulesDir == event.Name || strings.HasPrefix(rulesDir, event.Name+string(filepath.Separator)) {
+				changed = true
+				continue
+			}
+			rulesDirs = append(rulesDirs, rulesDir)
+		}
+	} else {
+		rulesDirs = append(rulesDirs, w.config.RulesDirs...)
+	}
+	if event.Op&fsnotify.Create != 0 {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			created, err := w.watchDirs(event.Name)
+			if err != nil {
+				return false, err
+			}
+			for _, rulesDir := range created {
+				if !known[rulesDir] {
+					rulesDirs = append(rulesDirs, rulesDir)
+					changed = true
+				}
+			}
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	sortRulesDirs(rulesDirs)
+	w.config.RulesDirs = rulesDirs
+	return true, w.config.reloadRules()
+}
+
+// handleEvent updates the loaded rules for a file system event and reports
+// whether a rebuild is needed
+func (w *Watcher) handleEvent(event fsnotify.Event) bool {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+		return false
+	}
+
+	if expected, ok := w.outputs[event.Name]; ok {
+		if data, err := os.ReadFile(event.Name); err == nil && sha256.Sum256(data) == expected {
+			return false
+		}
+		w.config.logf(slog.LevelWarn, "Warning: %s was modified outside syncai, rebuilding to restore it", event.Name)
+		return true
+	}
+	if !w.config.isSourcePath(event.Name) {
+		// A directory that adds or removes rules directories, or an
+		// unrelated file next to a watched output or in the project
+		changed, err := w.updateRulesDirs(event)
+		if err != nil {
+			w.config.logf(slog.LevelError, "Failed to reload config: %v", err)
+			return false
+		}
+		if changed {
+			fmt.Fprintf(w.config.output(), "Rules directories changed: %s\n", event.Name)
+		}
+		return changed
+	}
+	if event.Op&fsnotify.Create != 0 {
+		// Watch directories created inside a rules directory too
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if _, err := w.watchDirs(event.Name); err != nil {
+				w.config.logf(slog.LevelError, "Failed to watch %s: %v", event.Name, err)
+			}
+		}
+	}
+
+	fmt.Fprintf(w.config.output(), "File modified: %s\n", event.Name)
+	if err := w.config.applyChange(event); err != nil {
+		w.config.logf(slog.LevelError, "Failed to reload config: %v", err)
+		return false
+	}
+	return true
+}
+
+// build builds the tools once. In dry-run mode the files are built in memory
+// and the planned writes are printed instead: all of them the first time, then
+// only those a change affected.
+func (w *Watcher) build() error {
+	if w.buildTools != nil {
+		return w.buildTools()
+	}
+	if !w.config.Options.DryRun {
+		return buildOnce(w.config, w.tools)
+	}
+
+	memory := NewMemoryFileWriter()
+	config := *w.config
+	config.Writer = memory
+	err := buildOnce(&config, w.tools)
+	reportDryRun(&config, memory, w.planned)
+	w.planned = memory.Files()
+	return err
+}
+
+func (w *Watcher) rebuild() {
+	if err := w.build(); err != nil {
+		w.config.logf(slog.LevelError, "Build failed: %v", err)
+	} else {
+		fmt.Fprintln(w.config.output(), "Build completed successfully")
+	}
+
+	if w.config.Options.WatchAll {
+		outputs, err := watchOutputs(w.watcher, w.config, w.tools)
+		if err != nil {
+			w.config.logf(slog.LevelError, "Failed to watch generated files: %v", err)
+			return
+		}
+		w.outputs = outputs
+	}
+}
+
+// watchOutputs watches the directories of the files generated by tools and
+// returns the expected hash of each generated file
+func watchOutputs(watcher *fsnotify.Watcher, config *ProjectConfig, tools []AITool) (map[string][sha256.Size]byte, error) {
+	config, err := config.applyTransformCmd()
+	if err != nil {
+		return nil, err
+	}
+	outputs := map[string][sha256.Size]byte{}
+	for _, tool := range tools {
+		memory, err := buildInMemory(config, tool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine outputs of %s: %w", tool.Name(), err)
+		}
+		for path, data := range memory.Files() {
+			outputs[path] = sha256.Sum256(data)
+		}
+	}
+
+	// Watch directories rather than files, as editors often replace files on save
+	watched := map[string]bool{}
+	for path := range outputs {
+		dir := filepath.Dir(path)
+		if watched[dir] {
+			continue
+		}
+		watched[dir] = true
+		if err := watcher.Add(dir); err != nil {
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	return outputs, nil
+}
+
+// isSourcePath reports whether path is .cursorrules, one of its fragments or
+// inside a rules directory. Paths inside a tool's output directory never are,
+// so writing generated files can't trigger another rebuild.
+func (c *ProjectConfig) isSourcePath(path string) bool {
+	if c.isCursorRulesPath(path) {
+		return true
+	}
+	if rel, err := filepath.Rel(c.RootPath, path); err == nil {
+		for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+			if isOutputDirName(part) {
+				return false
+			}
+		}
+	}
+	return c.inRulesDir(path)
+}
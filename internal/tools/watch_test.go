@@ -0,0 +1,245 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// writeRule writes a rule file with the given content, creating its directory
+func writeRule(t testing.TB, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestWatcher loads the project in the working directory and watches its
+// sources without building
+func newTestWatcher(t *testing.T, opts BuildOptions) *Watcher {
+	t.Helper()
+	config, err := loadBuildConfig(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Output = io.Discard
+	w := NewWatcher(config, nil)
+	if w.watcher, err = fsnotify.NewWatcher(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.watcher.Close() })
+	if err := w.addSourcePaths(); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestWatcherDiscoversRulesDirs(t *testing.T) {
+	root := t.TempDir()
+	writeRule(t, filepath.Join(root, ".cursor", "rules", "global.mdc"), "---\ndescription: Global\nalwaysApply: true\n---\nUse tabs.\n")
+	if err := os.MkdirAll(filepath.Join(root, "packages"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(root)
+	w := newTestWatcher(t, BuildOptions{NoCache: true})
+
+	// A package with its own rules is created under a watched directory
+	web := filepath.Join(root, "packages", "web")
+	rulesDir := filepath.Join(web, ".cursor", "rules")
+	writeRule(t, filepath.Join(rulesDir, "react.mdc"), "---\ndescription: React\nglobs: \"*.tsx\"\n---\nUse hooks.\n")
+	if !w.handleEvent(fsnotify.Event{Name: web, Op: fsnotify.Create}) {
+		t.Fatal("creating a rules directory didn't trigger a rebuild")
+	}
+	if !slices.Contains(w.config.RulesDirs, rulesDir) {
+		t.Errorf("rules directories = %v, want %s among them", w.config.RulesDirs, rulesDir)
+	}
+	if len(w.config.MdcFiles) != 2 {
+		t.Errorf("got %d rules, want 2", len(w.config.MdcFiles))
+	}
+	if !slices.Contains(w.watcher.WatchList(), rulesDir) {
+		t.Errorf("new rules directory %s isn't watched", rulesDir)
+	}
+
+	// Removing the package drops its rules again
+	if err := os.RemoveAll(web); err != nil {
+		t.Fatal(err)
+	}
+	if !w.handleEvent(fsnotify.Event{Name: web, Op: fsnotify.Remove}) {
+		t.Fatal("removing a rules directory didn't trigger a rebuild")
+	}
+	if slices.Contains(w.config.RulesDirs, rulesDir) {
+		t.Errorf("removed rules directory %s is still loaded", rulesDir)
+	}
+	if len(w.config.MdcFiles) != 1 {
+		t.Errorf("got %d rules, want 1", len(w.config.MdcFiles))
+	}
+
+	// Other directories don't change the rules
+	src := filepath.Join(root, "src")
+	if err := os.Mkdir(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if w.handleEvent(fsnotify.Event{Name: src, Op: fsnotify.Create}) {
+		t.Error("creating a directory without rules triggered a rebuild")
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe to write from the watcher while a test
+// reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatcherRebuildsPendingChangeOnStop(t *testing.T) {
+	root := t.TempDir()
+	rule := filepath.Join(root, ".cursor", "rules", "global.mdc")
+	writeRule(t, rule, "---\ndescription: Global\nalwaysApply: true\n---\nUse tabs.\n")
+	t.Chdir(root)
+	config, err := loadBuildConfig(BuildOptions{NoCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := &syncBuffer{}
+	config.Output = output
+
+	builds := 0
+	w := NewWatcher(config, nil)
+	// Long enough that only stopping can run the rebuild
+	w.Debounce = time.Hour
+	w.buildTools = func() error {
+		builds++
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- w.Start(ctx) }()
+
+	waitFor := func(text string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(output.String(), text) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %q in output:\n%s", text, output.String())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("Watching for changes")
+	writeRule(t, rule, "---\ndescription: Global\nalwaysApply: true\n---\nUse spaces.\n")
+	waitFor("File modified")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if builds != 2 {
+		t.Errorf("got %d builds, want the initial one and the pending one", builds)
+	}
+	if !strings.Contains(output.String(), "Build completed successfully") {
+		t.Errorf("pending rebuild didn't run before stopping:\n%s", output.String())
+	}
+}
+
+func TestWatcherIgnoresOutputDirs(t *testing.T) {
+	root := t.TempDir()
+	rule := filepath.Join(root, ".cursor", "rules", "global.mdc")
+	writeRule(t, rule, "---\ndescription: Global\nalwaysApply: true\n---\nUse tabs.\n")
+	writeFiles(t, root, map[string]string{
+		".roocode/global.md":    "Use tabs.\n",
+		"web/.roocode/react.md": "Use hooks.\n",
+		// A rules directory inside an output is a copy, not a source
+		".roocode/.cursor/rules/copy.mdc": "Use tabs.\n",
+	})
+	t.Chdir(root)
+	w := newTestWatcher(t, BuildOptions{NoCache: true})
+
+	tests := []struct {
+		path    string
+		op      fsnotify.Op
+		rebuild bool
+	}{
+		{path: ".roocode/global.md", op: fsnotify.Write},
+		{path: "web/.roocode/react.md", op: fsnotify.Create},
+		{path: ".roocode/.cursor/rules/copy.mdc", op: fsnotify.Write},
+		{path: ".roocode/.cursor", op: fsnotify.Create},
+		{path: "README.md", op: fsnotify.Write},
+		{path: ".cursor/rules/global.mdc", op: fsnotify.Write, rebuild: true},
+		{path: ".cursorrules", op: fsnotify.Create, rebuild: true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(root, filepath.FromSlash(tt.path))
+		if got := w.handleEvent(fsnotify.Event{Name: path, Op: tt.op}); got != tt.rebuild {
+			t.Errorf("%s %s: rebuild = %v, want %v", tt.op, tt.path, got, tt.rebuild)
+		}
+	}
+	if len(w.config.RulesDirs) != 1 {
+		t.Errorf("rules directories %v, want only the source one", w.config.RulesDirs)
+	}
+	for _, path := range w.watcher.WatchList() {
+		if strings.Contains(path, ".roocode") {
+			t.Errorf("output directory %s is watched for sources", path)
+		}
+	}
+}
+
+func TestWatcherSkipsHeavyDirs(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".cursor/rules/global.mdc":      "---\ndescription: Global\nalwaysApply: true\n---\nUse tabs.\n",
+		".gitignore":                    "build/\n/.cursor\n",
+		"node_modules/pkg/index.js":     "",
+		"node_modules/pkg/lib/util.js":  "",
+		"vendor/example.com/mod/mod.go": "",
+		"build/out/app.js":              "",
+		"src/app.ts":                    "",
+	})
+	t.Chdir(root)
+	w := newTestWatcher(t, BuildOptions{NoCache: true})
+
+	watched := w.watcher.WatchList()
+	for _, dir := range []string{"node_modules", "node_modules/pkg", "vendor", "vendor/example.com", "build", "build/out"} {
+		if slices.Contains(watched, filepath.Join(root, filepath.FromSlash(dir))) {
+			t.Errorf("%s is watched", dir)
+		}
+	}
+	// The rules directory is watched even though .gitignore ignores it
+	for _, dir := range []string{"src", ".cursor", ".cursor/rules"} {
+		if !slices.Contains(watched, filepath.Join(root, filepath.FromSlash(dir))) {
+			t.Errorf("%s isn't watched", dir)
+		}
+	}
+
+	// A rules directory created in a dependency isn't picked up
+	pkg := filepath.Join(root, "node_modules", "other")
+	writeRule(t, filepath.Join(pkg, ".cursor", "rules", "dep.mdc"), "---\ndescription: Dependency\nalwaysApply: true\n---\nUse spaces.\n")
+	if w.handleEvent(fsnotify.Event{Name: pkg, Op: fsnotify.Create}) {
+		t.Error("creating rules in node_modules triggered a rebuild")
+	}
+}
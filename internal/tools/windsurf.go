@@ -1,67 +1,57 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
 type WindSurf struct{}
 
+// windsurfFormat is the layout of .windsurfrules
+var windsurfFormat = markdownFormat{
+	GlobalHeading:  "# Global Rules\n",
+	ContextHeading: "# Context-specific Rules\n\n",
+	RuleLevel:      2,
+	GlobsLabel:     "Applies to",
+}
+
 func (w *WindSurf) Name() string {
 	return "windsurf"
 }
 
+func (w *WindSurf) Capabilities() ToolConfig {
+	return ToolConfig{SupportsConditionalRules: false}
+}
+
 func (w *WindSurf) Build(config *ProjectConfig) error {
-	fmt.Printf("Building WindSurf configuration...\n")
+	fmt.Fprintf(config.output(), "Building WindSurf configuration...\n")
 	
 	// WindSurf uses .windsurfrules file
-	windsurfRulesPath := filepath.Join(config.RootPath, ".windsurfrules")
-	
-	var content strings.Builder
+	windsurfRulesPath := filepath.Join(config.outputDir(w.Name()), ".windsurfrules")
 	
-	// Add global rules from .cursorrules
-	if config.CursorRules != "" {
-		content.WriteString("# Global Rules\n")
-		content.WriteString(config.CursorRules)
-		content.WriteString("\n\n")
-	}
+	output := buildCombinedMarkdown(config, w.Name(), windsurfFormat)
 	
-	// Add MDC files content
-	if len(config.MdcFiles) > 0 {
-		content.WriteString("# Context-specific Rules\n\n")
-		for _, mdcFile := range config.MdcFiles {
-			if mdcFile.Description != "" {
-				content.WriteString(fmt.Sprintf("## %s\n", mdcFile.Description))
-			}
-			if len(mdcFile.Globs) > 0 {
-				content.WriteString(fmt.Sprintf("**Applies to:** %s\n", strings.Join(mdcFile.Globs, ", ")))
-			}
-			if mdcFile.AlwaysApply {
-				content.WriteString("**Always Apply:** Yes\n")
-			}
-			content.WriteString("\n")
-			content.WriteString(mdcFile.Content)
-			content.WriteString("\n\n")
-		}
+	if output == "" {
+		fmt.Fprintf(config.output(), "  ⚠ No rules found to generate WindSurf configuration\n")
+		return config.noRules(w.Name())
 	}
 	
-	if content.Len() == 0 {
-		fmt.Printf("  ⚠ No rules found to generate WindSurf configuration\n")
-		return nil
+	if err := config.writer().MkdirAll(filepath.Dir(windsurfRulesPath), config.dirMode()); err != nil {
+		return fmt.Errorf("failed to create directory for .windsurfrules: %w", err)
 	}
 	
-	err := os.WriteFile(windsurfRulesPath, []byte(content.String()), 0644)
+	err := config.writer().WriteFile(windsurfRulesPath, []byte(output), config.fileMode())
 	if err != nil {
 		return fmt.Errorf("failed to write .windsurfrules: %w", err)
 	}
 	
-	fmt.Printf("  ✓ Generated .windsurfrules\n")
+	fmt.Fprintf(config.output(), "  ✓ Generated .windsurfrules\n")
 	return nil
 }
 
-func (w *WindSurf) Import(rootPath string) (*ProjectConfig, error) {
+func (w *WindSurf) Import(ctx context.Context, rootPath string) (*ProjectConfig, error) {
 	config := &ProjectConfig{
 		RootPath: rootPath,
 	}
@@ -69,7 +59,7 @@ func (w *WindSurf) Import(rootPath string) (*ProjectConfig, error) {
 	// WindSurf uses .windsurfrules file
 	windsurfRulesPath := filepath.Join(rootPath, ".windsurfrules")
 	if data, err := os.ReadFile(windsurfRulesPath); err == nil {
-		config.CursorRules = string(data)
+		config.CursorRules, config.MdcFiles = splitCombinedMarkdown(string(data), windsurfFormat)
 	}
 	
 	return config, nil
@@ -0,0 +1,275 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// yamlLine is a non-empty, comment-stripped line of a YAML document
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// parseYAML parses the subset of YAML used by syncai settings files: nested
+// block mappings and sequences, flow lists/maps and plain or quoted scalars.
+// Mappings are returned as map[string]interface{}, sequences as []interface{}
+// and scalars as string.
+func parseYAML(data string) (map[string]interface{}, error) {
+	lines := []yamlLine{}
+	for i, raw := range strings.Split(data, "\n") {
+		text := stripYAMLComment(strings.TrimRight(raw, " \t\r"))
+		if strings.TrimSpace(text) == "" || strings.TrimSpace(text) == "---" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(text, " "), "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+		lines = append(lines, yamlLine{number: i + 1, indent: indent, text: strings.TrimSpace(text)})
+	}
+
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	value, next, err := parseYAMLBlock(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[next].number)
+	}
+
+	mapping, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("top level must be a mapping")
+	}
+	return mapping, nil
+}
+
+// parseYAMLBlock parses the block starting at lines[start] whose lines are
+// indented by exactly indent, returning the index of the first line after it
+func parseYAMLBlock(lines []yamlLine, start, indent int) (interface{}, int, error) {
+	if isYAMLListItem(lines[start].text) {
+		return parseYAMLSequence(lines, start, indent)
+	}
+	return parseYAMLMapping(lines, start, indent)
+}
+
+// isYAMLListItem reports whether a line starts an item of a block sequence
+func isYAMLListItem(text string) bool {
+	return strings.HasPrefix(text, "- ") || text == "-"
+}
+
+func parseYAMLSequence(lines []yamlLine, start, indent int) (interface{}, int, error) {
+	items := []interface{}{}
+	i := start
+	for i < len(lines) && lines[i].indent == indent && isYAMLListItem(lines[i].text) {
+		line := lines[i]
+
+		item := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		i++
+		if item == "" {
+			if i < len(lines) && lines[i].indent > indent {
+				value, next, err := parseYAMLBlock(lines, i, lines[i].indent)
+				if err != nil {
+					return nil, next, err
+				}
+				items = append(items, value)
+				i = next
+			} else {
+				items = append(items, "")
+			}
+			continue
+		}
+
+		value, err := parseYAMLFlow(item, line.number)
+		if err != nil {
+			return nil, i, err
+		}
+		items = append(items, value)
+	}
+	return items, i, nil
+}
+
+func parseYAMLMapping(lines []yamlLine, start, indent int) (interface{}, int, error) {
+	mapping := map[string]interface{}{}
+	i := start
+	for i < len(lines) && lines[i].indent == indent {
+		line := lines[i]
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, i, fmt.Errorf("line %d: expected \"key: value\"", line.number)
+		}
+
+		i++
+		if rest == "" {
+			// A sequence may be indented under its key or written at the
+			// key's own indent
+			if i < len(lines) && (lines[i].indent > indent || (lines[i].indent == indent && isYAMLListItem(lines[i].text))) {
+				value, next, err := parseYAMLBlock(lines, i, lines[i].indent)
+				if err != nil {
+					return nil, next, err
+				}
+				mapping[key] = value
+				i = next
+			} else {
+				mapping[key] = ""
+			}
+			continue
+		}
+
+		value, err := parseYAMLFlow(rest, line.number)
+		if err != nil {
+			return nil, i, err
+		}
+		mapping[key] = value
+	}
+	return mapping, i, nil
+}
+
+// parseYAMLFlow parses an inline value: a flow list, a flow map or a scalar
+func parseYAMLFlow(text string, lineNumber int) (interface{}, error) {
+	value, rest, err := parseYAMLFlowValue(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+	}
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("line %d: unexpected %q", lineNumber, strings.TrimSpace(rest))
+	}
+	return value, nil
+}
+
+func parseYAMLFlowValue(text string) (interface{}, string, error) {
+	text = strings.TrimLeft(text, " ")
+	if text == "" {
+		return "", "", nil
+	}
+
+	switch text[0] {
+	case '[':
+		items := []interface{}{}
+		rest := strings.TrimLeft(text[1:], " ")
+		for !strings.HasPrefix(rest, "]") {
+			if rest == "" {
+				return nil, "", fmt.Errorf("unterminated list")
+			}
+			item, next, err := parseYAMLFlowValue(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			items = append(items, item)
+			rest = strings.TrimLeft(next, " ")
+			rest = strings.TrimLeft(strings.TrimPrefix(rest, ","), " ")
+		}
+		return items, rest[1:], nil
+	case '{':
+		mapping := map[string]interface{}{}
+		rest := strings.TrimLeft(text[1:], " ")
+		for !strings.HasPrefix(rest, "}") {
+			colon := strings.Index(rest, ":")
+			if colon < 0 {
+				return nil, "", fmt.Errorf("unterminated map")
+			}
+			key := unquoteYAML(strings.TrimSpace(rest[:colon]))
+			value, next, err := parseYAMLFlowValue(rest[colon+1:])
+			if err != nil {
+				return nil, "", err
+			}
+			mapping[key] = value
+			rest = strings.TrimLeft(next, " ")
+			rest = strings.TrimLeft(strings.TrimPrefix(rest, ","), " ")
+		}
+		return mapping, rest[1:], nil
+	case '"', '\'':
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return text[1 : end+1], text[end+2:], nil
+	default:
+		end := strings.IndexAny(text, ",]}")
+		if end < 0 {
+			end = len(text)
+		}
+		return strings.TrimSpace(text[:end]), text[end:], nil
+	}
+}
+
+// splitYAMLKey splits "key: value" into its key and the (possibly empty) value
+func splitYAMLKey(text string) (string, string, bool) {
+	colon := strings.Index(text, ":")
+	if colon <= 0 || (colon+1 < len(text) && text[colon+1] != ' ') {
+		return "", "", false
+	}
+	return unquoteYAML(strings.TrimSpace(text[:colon])), strings.TrimSpace(text[colon+1:]), true
+}
+
+func unquoteYAML(text string) string {
+	if len(text) >= 2 && (text[0] == '"' || text[0] == '\'') && text[len(text)-1] == text[0] {
+		return text[1 : len(text)-1]
+	}
+	return text
+}
+
+// stripYAMLComment removes a trailing "# comment" that is not inside quotes
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch {
+		case quote != 0:
+			if text[i] == quote {
+				quote = 0
+			}
+		case text[i] == '"' || text[i] == '\'':
+			quote = text[i]
+		case text[i] == '#' && (i == 0 || text[i-1] == ' '):
+			return strings.TrimRight(text[:i], " ")
+		}
+	}
+	return text
+}
+
+// yamlStringList converts a parsed YAML value to a list of strings. A single
+// scalar is treated as a list of one element.
+func yamlStringList(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return []string{v}, nil
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of strings")
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("expected a list of strings")
+	}
+}
+
+// yamlBool converts a parsed YAML scalar to a boolean
+func yamlBool(value interface{}) (bool, error) {
+	s, ok := value.(string)
+	if !ok {
+		return false, fmt.Errorf("expected a boolean")
+	}
+	switch strings.ToLower(s) {
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off":
+		return false, nil
+	default:
+		return false, fmt.Errorf("expected a boolean, got %q", s)
+	}
+}
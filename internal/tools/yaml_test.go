@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			data: "",
+			want: map[string]interface{}{},
+		},
+		{
+			name: "scalars",
+			data: "rulesDir: .cursor/rules\nmaxTokens: 4000\nbanner: true\nempty:\n",
+			want: map[string]interface{}{"rulesDir": ".cursor/rules", "maxTokens": "4000", "banner": "true", "empty": ""},
+		},
+		{
+			name: "quoting",
+			data: "double: \"a: b # c\"\nsingle: 'x, y'\n\"quoted key\": value\n",
+			want: map[string]interface{}{"double": "a: b # c", "single": "x, y", "quoted key": "value"},
+		},
+		{
+			name: "comments",
+			data: "# settings\n---\nstyle: xml # inline\n\n  # indented comment\nhash: a#b\n",
+			want: map[string]interface{}{"style": "xml", "hash": "a#b"},
+		},
+		{
+			name: "nested maps",
+			data: "tools:\n  cursor:\n    version: legacy\n  roo-code:\n    dir: dist\nstyle: markdown\n",
+			want: map[string]interface{}{
+				"tools": map[string]interface{}{
+					"cursor":   map[string]interface{}{"version": "legacy"},
+					"roo-code": map[string]interface{}{"dir": "dist"},
+				},
+				"style": "markdown",
+			},
+		},
+		{
+			name: "indented list",
+			data: "tools:\n  - cursor\n  - claude-code\n",
+			want: map[string]interface{}{"tools": []interface{}{"cursor", "claude-code"}},
+		},
+		{
+			name: "compact list",
+			data: "tools:\n- cursor\n- claude-code\nstyle: xml\n",
+			want: map[string]interface{}{"tools": []interface{}{"cursor", "claude-code"}, "style": "xml"},
+		},
+		{
+			name: "compact list in a nested map",
+			data: "lint:\n  ignore:\n  - long-rule\n  - nesting\n  strict: yes\n",
+			want: map[string]interface{}{
+				"lint": map[string]interface{}{"ignore": []interface{}{"long-rule", "nesting"}, "strict": "yes"},
+			},
+		},
+		{
+			name: "list of maps",
+			data: "includes:\n  -\n    url: https://example.com/rules.tar.gz\n",
+			want: map[string]interface{}{
+				"includes": []interface{}{map[string]interface{}{"url": "https://example.com/rules.tar.gz"}},
+			},
+		},
+		{
+			name: "flow values",
+			data: "ruleExtensions: [.mdc, \".md\"]\nlint: {long-rule: false, nesting: true}\nnone: []\n",
+			want: map[string]interface{}{
+				"ruleExtensions": []interface{}{".mdc", ".md"},
+				"lint":           map[string]interface{}{"long-rule": "false", "nesting": "true"},
+				"none":           []interface{}{},
+			},
+		},
+		{name: "missing colon", data: "tools\n", wantErr: true},
+		{name: "tab indentation", data: "tools:\n\t- cursor\n", wantErr: true},
+		{name: "unterminated list", data: "tools: [cursor\n", wantErr: true},
+		{name: "unterminated string", data: "style: \"xml\n", wantErr: true},
+		{name: "top level list", data: "- cursor\n", wantErr: true},
+		{name: "bad indentation", data: "a: b\n  c: d\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSettingsCompactToolList(t *testing.T) {
+	settings, err := parseSettings("tools:\n- cursor\n- claude-code\nruleExtensions:\n- .mdc\n- md\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"cursor", "claude-code"}; !reflect.DeepEqual(settings.Tools, want) {
+		t.Errorf("got tools %v, want %v", settings.Tools, want)
+	}
+	if want := []string{".mdc", ".md"}; !reflect.DeepEqual(settings.RuleExtensions, want) {
+		t.Errorf("got rule extensions %v, want %v", settings.RuleExtensions, want)
+	}
+}
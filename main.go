@@ -1,24 +1,58 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"runtime/pprof"
+	"runtime/trace"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dudykr/syncai/internal/tools"
 	"github.com/spf13/cobra"
 )
 
+// Exit codes, documented in exitCodesHelp
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitInvalid     = 2
+	exitNoRules     = 3
+	exitBuildFailed = 4
+	exitOutdated    = 5
+)
+
+const exitCodesHelp = `
+
+Exit codes:
+  0  success
+  1  generic error
+  2  settings or rules failed to parse or validate
+  3  no rules found
+  4  one or more AI tools failed to build
+  5  generated files are out of date (check)`
+
 func main() {
 	var rootCmd = &cobra.Command{
 		Use:   "syncai",
 		Short: "Synchronize custom instructions across different AI tools",
 		Long:  `A CLI tool to convert and synchronize custom instructions between different AI tools like Cursor, WindSurf, Roo Code, Cline, and Claude Code.`,
+		// Errors are reported below so that build failures can be printed per tool
+		SilenceErrors: true,
+		// Flags and arguments are parsed by now, so later errors, such as an
+		// invalid settings file, aren't usage errors
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			cmd.SilenceUsage = true
+		},
 	}
 
 	var buildCmd = &cobra.Command{
 		Use:   "build",
 		Short: "Build AI tool configuration files",
-		Long:  `Build configuration files for specified AI tools from .cursorrules and .cursor/rules/*.mdc files.`,
+		Long:  `Build configuration files for specified AI tools from .cursorrules and .cursor/rules/*.mdc files.` + exitCodesHelp,
 		RunE:  runBuild,
 	}
 
@@ -29,31 +63,774 @@ func main() {
 		RunE:  runImport,
 	}
 
+	var statsCmd = &cobra.Command{
+		Use:   "stats",
+		Short: "Report the size of generated AI tool configurations",
+		Long:  `Report the size of the content each AI tool would generate, and the size of each rule, with a rough token estimate (4 characters per token). No files are written.`,
+		RunE:  runStats,
+	}
+
+	var renameCmd = &cobra.Command{
+		Use:   "rename <old-name> <new-name>",
+		Short: "Rename an MDC rule",
+		Long:  `Rename an MDC rule by updating its name field and file name, and remove generated files left behind by the old name. A rule's name is its name field, or else its file name without extension.`,
+		Args:  cobra.ExactArgs(2),
+		RunE:  runRename,
+	}
+
+	var checkCmd = &cobra.Command{
+		Use:   "check",
+		Short: "Validate rules and verify generated files are up to date",
+		Long:  `Validate the rules like the validate command, then build each AI tool in memory and compare the result with the generated files on disk. Exits with an error when a rule has a problem or a generated file is missing or out of date, making it a single gate for CI. No files are written.` + exitCodesHelp,
+		RunE:  runCheck,
+	}
+
+	var describeCmd = &cobra.Command{
+		Use:   "describe <rule-name>",
+		Short: "Show where a rule ends up for each AI tool",
+		Long:  `Show, for each AI tool, the generated files a rule contributes to and how: as a separate file, inlined into the global or context-specific rules of a combined file, or dropped. A rule's name is its name field, or else its file name without extension. No files are written.`,
+		Args:  cobra.ExactArgs(1),
+		RunE:  runDescribe,
+	}
+
+	var validateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Check rules for problems",
+		Long:  `Check the rules for problems, such as two rules with the same name. Exits with an error when a problem is found. No files are written.` + exitCodesHelp,
+		RunE:  runValidate,
+	}
+
+	var serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Preview generated files in the browser",
+		Long:  `Run a local HTTP server that renders the files each AI tool would generate as HTML. The files are built in memory, so nothing is written, and rebuilt whenever a rule changes; open pages reload automatically. Stop it with Ctrl+C.`,
+		RunE:  runServe,
+	}
+
+	var bundleCmd = &cobra.Command{
+		Use:   "bundle",
+		Short: "Package the rules into a zip archive, or extract one",
+		Long:  `Package .cursorrules, the .cursorrules.d fragments and every .cursor/rules file into a zip archive, keeping their paths relative to the project root, so a rule set can be shared with other repositories. With --extract, unpack such an archive into the current project instead.`,
+		Example: `  syncai bundle -o rules.zip
+  syncai bundle --extract rules.zip`,
+		RunE: runBundle,
+	}
+
+	var orphansCmd = &cobra.Command{
+		Use:   "orphans",
+		Short: "List generated files the current rules no longer produce",
+		Long:  `List files in the directories AI tools write their rules to, such as .roocode, that no tool would generate from the current rules, for example the context file of a rule that was renamed. Nothing is removed, so the files can be reviewed before deleting them.`,
+		RunE:  runOrphans,
+	}
+
+	var listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the rules and the files each applies to",
+		Long:  `List each rule with its globs and the files of the project they match. Files ignored by ` + tools.CursorIgnoreFileName + ` are left out and counted separately. Nothing is written.`,
+		RunE:  runList,
+	}
+
+	var lintCmd = &cobra.Command{
+		Use:   "lint",
+		Short: "Check rules for content that is hard for an LLM to follow",
+		Long:  `Check the content of the rules for anti-patterns: very long rules, rules that contradict each other, deeply nested headings or lists, and rules that are mostly code with little guidance. Issues are advisory and don't fail the command. Checks can be disabled under lint: in ` + tools.SettingsFileName + `.`,
+		RunE:  runLint,
+	}
+
+	var configPath string
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Settings file to use instead of ./"+tools.SettingsFileName)
+	var noCache bool
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Don't read or write "+tools.CacheDirName+"; fetch into a temporary directory instead")
+	var rulesDir string
+	rootCmd.PersistentFlags().StringVar(&rulesDir, "rules-dir", "", "Directory rule files are read from, relative to the project root and to folders with their own rules (default from settings, else "+tools.DefaultRulesDir+")")
+	var logFormat string
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", tools.LogFormatText, "Progress output format: text, or json for one JSON log record per line on stderr")
+
 	var targets []string
+	var only string
 	var watch bool
+	var watchAll bool
+	var dedupeContent bool
+	var noGlobalRules []string
+	var prune bool
+	var includeRoots []string
+	var includeRepos []string
+	var offline bool
+	var dryRun bool
+	var dryRunJSON bool
+	var maxTokens int
+	var maxRules int
+	var printTool string
+	var banner bool
+	var outputDirs []string
+	var commit bool
+	var toolDirs map[string]string
+	var toolVersions map[string]string
+	var manageGitattributes bool
+	var transformCmd string
+	var verbose bool
+	var backup bool
+	var annotateSources bool
+	var fileMode string
+	var style string
+	var outputLayout string
+	var relativeGlobs string
+	var sortRules string
+	var fileLists bool
+	var rulePrefix string
+	var normalizeWhitespace bool
+	var ruleSuffix string
+	var noMdc bool
+	var splitGlobs bool
+	var toc bool
+	var mergeGlobs bool
+	var filterByLanguage bool
+	var followSymlinks bool
+	var normalizeHeadings bool
+	var watchDebounceMax time.Duration
+	var allowOutsideRoot bool
+	var report string
+	var noFolderRules bool
+	var dirMode string
+	var readonly bool
 
-	buildCmd.Flags().StringSliceVarP(&targets, "target", "t", []string{}, "Target AI tools (cursor, windsurf, roo-code, cline, claude-code)")
+	buildCmd.Flags().StringSliceVarP(&targets, "target", "t", []string{}, "Target AI tools (cursor, windsurf, roo-code, cline, claude-code, agents)")
+	buildCmd.Flags().StringSlice("exclude-target", []string{}, "AI tools to leave out of the --target tools, or of all tools without --target (repeatable)")
+	buildCmd.Flags().StringVar(&only, "only", "", "Build exactly one AI tool")
 	buildCmd.Flags().BoolVarP(&watch, "watch", "w", false, "Watch for changes and rebuild automatically")
+	buildCmd.Flags().BoolVar(&watchAll, "watch-all", false, "Like --watch, and also restore generated files edited outside syncai")
+	buildCmd.Flags().BoolVar(&dedupeContent, "dedupe-content", false, "Remove repeated paragraphs from combined output files")
+	buildCmd.Flags().StringSliceVar(&noGlobalRules, "no-global-rules", []string{}, "AI tools to build without the global .cursorrules")
+	buildCmd.Flags().StringSlice("require-rules", []string{}, "AI tools whose build fails when they have no rules to generate output from")
+	buildCmd.Flags().BoolVar(&prune, "prune", false, "Remove generated files of AI tools that are not targeted")
+	buildCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be written or removed without changing any files")
+	buildCmd.Flags().BoolVar(&dryRunJSON, "json", false, "With --dry-run, print the planned operations as a JSON array on stdout: tool, path, action (create, update, unchanged or delete) and bytes")
+	buildCmd.Flags().StringSliceVar(&includeRoots, "include-root", []string{}, "URL of a .tar.gz archive with additional rules")
+	buildCmd.Flags().StringSliceVar(&includeRepos, "include-repo", []string{}, "Git repository with additional rules")
+	buildCmd.Flags().BoolVar(&offline, "offline", false, "Use cached copies of --include-root/--include-repo rules without fetching")
+	buildCmd.Flags().IntVar(&maxTokens, "max-tokens", 0, "Drop conditional rules from combined output files until they fit this estimated token budget")
+	buildCmd.Flags().IntVar(&maxRules, "max-rules", 0, "Fail when more MDC rules than this are found, to catch an unexpectedly large rules tree")
+	buildCmd.Flags().StringVar(&printTool, "print", "", "Write the content generated for one AI tool to stdout instead of to files")
+	buildCmd.Flags().BoolVar(&banner, "banner", false, "Add an AUTO-GENERATED banner to generated files")
+	buildCmd.Flags().BoolVar(&readonly, "readonly", false, "Make generated files read-only; they are made writable again on the next build")
+	buildCmd.Flags().StringArrayVar(&outputDirs, "output-dir", nil, "Write generated files to this directory, such as a git worktree, instead of the project root (repeatable, to write the same files to several directories)")
+	buildCmd.Flags().BoolVar(&commit, "commit", false, "Commit the generated files in --output-dir")
+	buildCmd.Flags().BoolVar(&manageGitattributes, "manage-gitattributes", false, "Mark generated files as linguist-generated in .gitattributes so diffs collapse them")
+	buildCmd.Flags().StringVar(&transformCmd, "transform-cmd", "", "Shell command that rewrites the parsed rules: it reads them as JSON on stdin and prints the rules to build from as JSON")
+	buildCmd.Flags().StringToStringVar(&toolVersions, "tool-version", map[string]string{}, "Output format version of one AI tool, e.g. cursor=legacy for a single .cursorrules (repeatable)")
+	buildCmd.Flags().StringToStringVar(&toolDirs, "tool-dir", map[string]string{}, "Write one AI tool's files to another directory, e.g. claude-code=docs (repeatable)")
+	buildCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show how each AI tool represents rule features it doesn't support")
+	buildCmd.Flags().BoolVar(&backup, "backup", false, "Save the previous content of generated files that change to <file>.bak")
+	buildCmd.Flags().BoolVar(&annotateSources, "annotate-sources", false, "Precede each rule in combined output files with a comment naming its source file")
+	buildCmd.Flags().StringVar(&report, "report", "", "Write a JSON report of the files each tool wrote, warnings and duration to this file")
+	buildCmd.Flags().BoolVar(&allowOutsideRoot, "allow-outside-root", false, "Include rules that are symlinks to files outside the project root, placed in an external/ subdirectory by tools with folder rules")
+	buildCmd.Flags().DurationVar(&watchDebounceMax, "watch-debounce-max", 0, "Rebuild at the latest this long after the first change in watch mode, even while changes keep arriving (e.g. 2s)")
+	buildCmd.Flags().BoolVar(&normalizeHeadings, "normalize-headings", false, "Demote headings in rule content below the section and rule headings of generated files")
+	buildCmd.Flags().BoolVar(&mergeGlobs, "merge-globs", false, "Combine rules with the same file patterns into one rule in combined output files, listing the patterns once")
+	buildCmd.Flags().BoolVar(&toc, "toc", false, "Add a table of contents linking to each rule to combined output files")
+	buildCmd.Flags().BoolVar(&splitGlobs, "split-globs", false, "Write a rule with several globs as one rule per glob for tools that scope rules to files natively")
+	buildCmd.Flags().BoolVar(&noMdc, "no-mdc", false, "Build from the global rules only, leaving out every MDC rule")
+	buildCmd.Flags().BoolVar(&followSymlinks, "follow-symlinks", false, "Also look for .cursor directories through symlinked directories, such as a .cursor symlink")
+	buildCmd.Flags().BoolVar(&filterByLanguage, "filter-by-language", false, "Leave out rules whose languages field names no language found in the project's source files")
+	buildCmd.Flags().BoolVar(&noFolderRules, "no-folder-rules", false, "Leave out folder rules: rules scoped as folder and rules in nested .cursor directories")
+	buildCmd.Flags().BoolVar(&normalizeWhitespace, "normalize-whitespace", true, "Strip trailing whitespace and shorten runs of blank lines to two in generated files, keeping code blocks verbatim")
+	buildCmd.Flags().StringVar(&rulePrefix, "rule-prefix", "", "Text to start every combined output file with, such as framing instructions (default from settings)")
+	buildCmd.Flags().StringVar(&ruleSuffix, "rule-suffix", "", "Text to end every combined output file with (default from settings)")
+	buildCmd.Flags().BoolVar(&fileLists, "file-lists", false, "List the files each rule applies to, minus those in "+tools.CursorIgnoreFileName+", next to its globs in combined outputs")
+	buildCmd.Flags().StringVar(&sortRules, "sort-rules", tools.SortPath, "Order of the rules in combined outputs: path, name, priority (by level) or none (as loaded)")
+	buildCmd.Flags().StringVar(&relativeGlobs, "relative-globs", tools.GlobsRoot, "How globs are written in combined outputs: root (relative to the project root), authored (as written in the rule) or absolute")
+	buildCmd.Flags().StringVar(&outputLayout, "output-layout", tools.LayoutMirror, "Where tools with folder rules write them: mirror (next to their folder) or flat (one directory, prefixed with the folder)")
+	buildCmd.Flags().StringVar(&style, "style", "", "Style of the rules in combined output files: markdown or xml (default from settings, else markdown)")
+	buildCmd.Flags().StringVar(&fileMode, "file-mode", "0644", "Permissions of generated files, in octal")
+	buildCmd.Flags().StringVar(&dirMode, "dir-mode", "0755", "Permissions of directories created for generated files, in octal")
+	// Diagnostics for slow builds, listed only by --help-advanced
+	buildCmd.Flags().String("profile", "", "Write a pprof CPU profile of the build to this file")
+	buildCmd.Flags().String("trace", "", "Write a Go execution trace of the build to this file")
+	buildCmd.Flags().MarkHidden("profile")
+	buildCmd.Flags().MarkHidden("trace")
+	buildCmd.Flags().Bool("help-advanced", false, "Show help including advanced flags")
+	buildCmd.MarkFlagsMutuallyExclusive("target", "only")
+	buildCmd.MarkFlagsMutuallyExclusive("report", "watch")
+	buildCmd.MarkFlagsMutuallyExclusive("json", "watch")
+	for _, flag := range []string{"target", "only", "exclude-target", "watch", "watch-all", "prune", "dry-run", "report"} {
+		buildCmd.MarkFlagsMutuallyExclusive("print", flag)
+	}
+
+	importCmd.Flags().Bool("to-mdc", false, "Write the imported rules to .cursor/rules/imported.mdc")
+	importCmd.Flags().String("from", "", "AI tool to import from with --to-mdc, instead of asking when several are found")
+	importCmd.RegisterFlagCompletionFunc("from", completeToolNames)
+
+	statsCmd.Flags().StringSliceP("target", "t", []string{}, "Target AI tools (cursor, windsurf, roo-code, cline, claude-code, agents)")
+
+	// Suggest the supported tool names for every flag that takes them. The
+	// completion command itself is provided by cobra.
+	for _, flag := range []string{"target", "only", "exclude-target", "print", "no-global-rules", "require-rules"} {
+		buildCmd.RegisterFlagCompletionFunc(flag, completeToolNames)
+	}
+	buildCmd.RegisterFlagCompletionFunc("tool-dir", completeToolDirs)
+	buildCmd.RegisterFlagCompletionFunc("tool-version", completeToolVersions)
+	statsCmd.RegisterFlagCompletionFunc("target", completeToolNames)
+	checkCmd.Flags().StringSliceP("target", "t", []string{}, "Target AI tools (cursor, windsurf, roo-code, cline, claude-code, agents)")
+	checkCmd.RegisterFlagCompletionFunc("target", completeToolNames)
+	describeCmd.Flags().StringSliceP("target", "t", []string{}, "Target AI tools (cursor, windsurf, roo-code, cline, claude-code, agents)")
+	describeCmd.RegisterFlagCompletionFunc("target", completeToolNames)
+	serveCmd.Flags().StringSliceP("target", "t", []string{}, "Target AI tools (cursor, windsurf, roo-code, cline, claude-code, agents)")
+	serveCmd.RegisterFlagCompletionFunc("target", completeToolNames)
+	serveCmd.Flags().String("addr", tools.DefaultServeAddr, "Address to listen on")
 
-	rootCmd.AddCommand(buildCmd, importCmd)
+	bundleCmd.Flags().StringP("output", "o", "rules.zip", "Archive to write")
+	bundleCmd.Flags().String("extract", "", "Archive to unpack into the current project instead of creating one")
+	bundleCmd.Flags().Bool("force", false, "With --extract, replace existing rule files whose content differs")
+	bundleCmd.MarkFlagsMutuallyExclusive("output", "extract")
+
+	orphansCmd.Flags().String("output-dir", "", "Directory the files were generated in, if not the project root")
+	orphansCmd.Flags().String("output-layout", tools.LayoutMirror, "Output layout the files were generated with: mirror or flat")
+
+	rootCmd.AddCommand(buildCmd, importCmd, statsCmd, renameCmd, describeCmd, validateCmd, lintCmd, listCmd, checkCmd, serveCmd, bundleCmd, orphansCmd)
 
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		var buildErr *tools.BuildError
+		if errors.As(err, &buildErr) {
+			for _, toolErr := range buildErr.Errors {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", toolErr)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+// exitCode returns the exit code for an error returned by a command
+func exitCode(err error) int {
+	var invalidErr *tools.InvalidError
+	var buildErr *tools.BuildError
+	switch {
+	case errors.As(err, &invalidErr):
+		return exitInvalid
+	case errors.Is(err, tools.ErrNoRules):
+		return exitNoRules
+	case errors.As(err, &buildErr):
+		return exitBuildFailed
+	case errors.Is(err, tools.ErrOutdated):
+		return exitOutdated
+	default:
+		return exitError
 	}
 }
 
 func runBuild(cmd *cobra.Command, args []string) error {
+	if helpAdvanced, _ := cmd.Flags().GetBool("help-advanced"); helpAdvanced {
+		cmd.Flags().Lookup("profile").Hidden = false
+		cmd.Flags().Lookup("trace").Hidden = false
+		return cmd.Help()
+	}
+
 	targets, _ := cmd.Flags().GetStringSlice("target")
+	configPath, _ := cmd.Flags().GetString("config")
+	rulesDir, _ := cmd.Flags().GetString("rules-dir")
 	watch, _ := cmd.Flags().GetBool("watch")
+	watchAll, _ := cmd.Flags().GetBool("watch-all")
+	dedupeContent, _ := cmd.Flags().GetBool("dedupe-content")
+	noGlobalRules, _ := cmd.Flags().GetStringSlice("no-global-rules")
+	requireRules, _ := cmd.Flags().GetStringSlice("require-rules")
+	prune, _ := cmd.Flags().GetBool("prune")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	dryRunJSON, _ := cmd.Flags().GetBool("json")
+	includeRoots, _ := cmd.Flags().GetStringSlice("include-root")
+	includeRepos, _ := cmd.Flags().GetStringSlice("include-repo")
+	offline, _ := cmd.Flags().GetBool("offline")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	maxTokens, _ := cmd.Flags().GetInt("max-tokens")
+	maxRules, _ := cmd.Flags().GetInt("max-rules")
+	banner, _ := cmd.Flags().GetBool("banner")
+	outputDirs, _ := cmd.Flags().GetStringArray("output-dir")
+	commit, _ := cmd.Flags().GetBool("commit")
+	toolDirs, _ := cmd.Flags().GetStringToString("tool-dir")
+	toolVersions, _ := cmd.Flags().GetStringToString("tool-version")
+	manageGitattributes, _ := cmd.Flags().GetBool("manage-gitattributes")
+	transformCmd, _ := cmd.Flags().GetString("transform-cmd")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	backup, _ := cmd.Flags().GetBool("backup")
+	annotateSources, _ := cmd.Flags().GetBool("annotate-sources")
+	fileModeValue, _ := cmd.Flags().GetString("file-mode")
+	style, _ := cmd.Flags().GetString("style")
+	outputLayout, _ := cmd.Flags().GetString("output-layout")
+	relativeGlobs, _ := cmd.Flags().GetString("relative-globs")
+	sortRules, _ := cmd.Flags().GetString("sort-rules")
+	fileLists, _ := cmd.Flags().GetBool("file-lists")
+	rulePrefix, _ := cmd.Flags().GetString("rule-prefix")
+	normalizeWhitespace, _ := cmd.Flags().GetBool("normalize-whitespace")
+	ruleSuffix, _ := cmd.Flags().GetString("rule-suffix")
+	noMdc, _ := cmd.Flags().GetBool("no-mdc")
+	splitGlobs, _ := cmd.Flags().GetBool("split-globs")
+	toc, _ := cmd.Flags().GetBool("toc")
+	mergeGlobs, _ := cmd.Flags().GetBool("merge-globs")
+	normalizeHeadings, _ := cmd.Flags().GetBool("normalize-headings")
+	watchDebounceMax, _ := cmd.Flags().GetDuration("watch-debounce-max")
+	allowOutsideRoot, _ := cmd.Flags().GetBool("allow-outside-root")
+	report, _ := cmd.Flags().GetString("report")
+	noFolderRules, _ := cmd.Flags().GetBool("no-folder-rules")
+	filterByLanguage, _ := cmd.Flags().GetBool("filter-by-language")
+	followSymlinks, _ := cmd.Flags().GetBool("follow-symlinks")
+	dirModeValue, _ := cmd.Flags().GetString("dir-mode")
+	readonly, _ := cmd.Flags().GetBool("readonly")
 
-	if len(targets) == 0 {
-		targets = []string{"cursor", "windsurf", "roo-code", "cline", "claude-code"}
+	fileMode, err := parseMode(fileModeValue)
+	if err != nil {
+		return fmt.Errorf("--file-mode: %w", err)
+	}
+	dirMode, err := parseMode(dirModeValue)
+	if err != nil {
+		return fmt.Errorf("--dir-mode: %w", err)
+	}
+
+	if style != "" && !tools.IsKnownStyle(style) {
+		return fmt.Errorf("--style must be %s or %s, got %q", tools.StyleMarkdown, tools.StyleXML, style)
+	}
+
+	if outputLayout != tools.LayoutMirror && outputLayout != tools.LayoutFlat {
+		return fmt.Errorf("--output-layout must be %s or %s, got %q", tools.LayoutMirror, tools.LayoutFlat, outputLayout)
+	}
+	switch sortRules {
+	case tools.SortPath, tools.SortName, tools.SortPriority, tools.SortNone:
+	default:
+		return fmt.Errorf("--sort-rules must be %s, %s, %s or %s, got %q", tools.SortPath, tools.SortName, tools.SortPriority, tools.SortNone, sortRules)
+	}
+	if relativeGlobs != tools.GlobsRoot && relativeGlobs != tools.GlobsAuthored && relativeGlobs != tools.GlobsAbsolute {
+		return fmt.Errorf("--relative-globs must be %s, %s or %s, got %q", tools.GlobsRoot, tools.GlobsAuthored, tools.GlobsAbsolute, relativeGlobs)
+	}
+
+	if watchDebounceMax < 0 {
+		return fmt.Errorf("--watch-debounce-max must not be negative, got %s", watchDebounceMax)
+	}
+
+	if maxTokens < 0 {
+		return fmt.Errorf("--max-tokens must not be negative, got %d", maxTokens)
+	}
+
+	if maxRules < 0 {
+		return fmt.Errorf("--max-rules must not be negative, got %d", maxRules)
+	}
+
+	targets, err = tools.ExpandToolPatterns(targets)
+	if err != nil {
+		return fmt.Errorf("--target: %w", err)
+	}
+
+	if cmd.Flags().Changed("only") {
+		only, _ := cmd.Flags().GetString("only")
+		if !tools.IsKnownTool(only) {
+			return fmt.Errorf("--only requires exactly one tool, got %q (valid tools: %s)", only, strings.Join(tools.ToolNames, ", "))
+		}
+		targets = []string{only}
+	}
+
+	excludeTargets, _ := cmd.Flags().GetStringSlice("exclude-target")
+	if targets, err = excludeTools(targets, excludeTargets); err != nil {
+		return err
+	}
+
+	if offline && noCache {
+		return fmt.Errorf("--offline reads the cache and cannot be used with --no-cache")
+	}
+
+	logFormat, err := getLogFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	if dryRunJSON && !dryRun {
+		return fmt.Errorf("--json requires --dry-run")
+	}
+
+	if commit && len(outputDirs) == 0 {
+		return fmt.Errorf("--commit requires --output-dir")
+	}
+
+	// A single directory is built directly; several are built in turn
+	outputDir := ""
+	if len(outputDirs) == 1 {
+		outputDir, outputDirs = outputDirs[0], nil
+	}
+	if len(outputDirs) > 1 {
+		for _, flag := range []string{"watch", "watch-all", "report", "json"} {
+			if cmd.Flags().Changed(flag) {
+				return fmt.Errorf("--%s can't be used with several --output-dir directories", flag)
+			}
+		}
+	}
+
+	for name, version := range toolVersions {
+		if !tools.IsKnownTool(name) {
+			return fmt.Errorf("--tool-version: unknown tool: %s", name)
+		}
+		if !tools.IsKnownToolVersion(name, version) {
+			if versions := tools.ToolVersions(name); len(versions) > 0 {
+				return fmt.Errorf("--tool-version: %s version must be one of %s, got %q", name, strings.Join(versions, ", "), version)
+			}
+			return fmt.Errorf("--tool-version: %s has a single output format", name)
+		}
+	}
+
+	for name := range toolDirs {
+		if !tools.IsKnownTool(name) {
+			return fmt.Errorf("--tool-dir: unknown tool: %s", name)
+		}
+	}
+
+	for _, name := range noGlobalRules {
+		if !tools.IsKnownTool(name) {
+			return fmt.Errorf("--no-global-rules: unknown tool: %s", name)
+		}
+	}
+
+	for _, name := range requireRules {
+		if !tools.IsKnownTool(name) {
+			return fmt.Errorf("--require-rules: unknown tool: %s", name)
+		}
+	}
+
+	opts := tools.BuildOptions{
+		ConfigPath:          configPath,
+		RulesDir:            rulesDir,
+		TransformCmd:        transformCmd,
+		Watch:               watch || watchAll,
+		WatchAll:            watchAll,
+		DedupeContent:       dedupeContent,
+		NoGlobalRules:       noGlobalRules,
+		RequireRules:        requireRules,
+		Prune:               prune,
+		DryRun:              dryRun,
+		DryRunJSON:          dryRunJSON,
+		IncludeRoots:        includeRoots,
+		IncludeRepos:        includeRepos,
+		Offline:             offline,
+		NoCache:             noCache,
+		MaxTokens:           maxTokens,
+		MaxRules:            maxRules,
+		OutputDir:           outputDir,
+		OutputDirs:          outputDirs,
+		Commit:              commit,
+		ToolDirs:            toolDirs,
+		ToolVersions:        toolVersions,
+		ManageGitattributes: manageGitattributes,
+		LogFormat:           logFormat,
+		Verbose:             verbose,
+		Backup:              backup,
+		AnnotateSources:     annotateSources,
+		FileMode:            fileMode,
+		Style:               style,
+		OutputLayout:        outputLayout,
+		RelativeGlobs:       relativeGlobs,
+		SortRules:           sortRules,
+		FileLists:           fileLists,
+		RulePrefix:          rulePrefix,
+		KeepWhitespace:      !normalizeWhitespace,
+		RuleSuffix:          ruleSuffix,
+		NoMdc:               noMdc,
+		SplitGlobs:          splitGlobs,
+		TOC:                 toc,
+		MergeGlobs:          mergeGlobs,
+		NormalizeHeadings:   normalizeHeadings,
+		WatchDebounceMax:    watchDebounceMax,
+		AllowOutsideRoot:    allowOutsideRoot,
+		Report:              report,
+		NoFolderRules:       noFolderRules,
+		FilterByLanguage:    filterByLanguage,
+		FollowSymlinks:      followSymlinks,
+		DirMode:             dirMode,
+		Banner:              banner,
+		Readonly:            readonly,
+	}
+
+	profilePath, _ := cmd.Flags().GetString("profile")
+	tracePath, _ := cmd.Flags().GetString("trace")
+	stopProfiling, err := startProfiling(profilePath, tracePath)
+	if err != nil {
+		return err
+	}
+	defer stopProfiling()
+
+	if cmd.Flags().Changed("print") {
+		printTool, _ := cmd.Flags().GetString("print")
+		if !tools.IsKnownTool(printTool) {
+			return fmt.Errorf("--print requires exactly one tool, got %q (valid tools: %s)", printTool, strings.Join(tools.ToolNames, ", "))
+		}
+		return tools.Print(printTool, opts, os.Stdout)
 	}
 
-	return tools.Build(targets, watch)
+	return tools.Build(targets, opts)
 }
 
 func runImport(cmd *cobra.Command, args []string) error {
-	return tools.Import()
+	toMdc, _ := cmd.Flags().GetBool("to-mdc")
+	from, _ := cmd.Flags().GetString("from")
+	if from != "" {
+		if !tools.IsKnownTool(from) {
+			return fmt.Errorf("--from: unknown tool: %s", from)
+		}
+		if !toMdc {
+			return fmt.Errorf("--from requires --to-mdc")
+		}
+	}
+	configPath, _ := cmd.Flags().GetString("config")
+	rulesDir, _ := cmd.Flags().GetString("rules-dir")
+	return tools.Import(tools.ImportOptions{ToMdc: toMdc, From: from, ConfigPath: configPath, RulesDir: rulesDir})
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	targets, _ := cmd.Flags().GetStringSlice("target")
+	configPath, _ := cmd.Flags().GetString("config")
+	rulesDir, _ := cmd.Flags().GetString("rules-dir")
+	targets, err := tools.ExpandToolPatterns(targets)
+	if err != nil {
+		return fmt.Errorf("--target: %w", err)
+	}
+	return tools.Stats(targets, tools.BuildOptions{ConfigPath: configPath, RulesDir: rulesDir})
+}
+
+func runRename(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+	rulesDir, _ := cmd.Flags().GetString("rules-dir")
+	return tools.Rename(args[0], args[1], tools.BuildOptions{ConfigPath: configPath, RulesDir: rulesDir})
+}
+
+func runDescribe(cmd *cobra.Command, args []string) error {
+	targets, _ := cmd.Flags().GetStringSlice("target")
+	configPath, _ := cmd.Flags().GetString("config")
+	rulesDir, _ := cmd.Flags().GetString("rules-dir")
+	targets, err := tools.ExpandToolPatterns(targets)
+	if err != nil {
+		return fmt.Errorf("--target: %w", err)
+	}
+	return tools.Describe(args[0], targets, tools.BuildOptions{ConfigPath: configPath, RulesDir: rulesDir})
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	targets, _ := cmd.Flags().GetStringSlice("target")
+	configPath, _ := cmd.Flags().GetString("config")
+	rulesDir, _ := cmd.Flags().GetString("rules-dir")
+	targets, err := tools.ExpandToolPatterns(targets)
+	if err != nil {
+		return fmt.Errorf("--target: %w", err)
+	}
+	logFormat, err := getLogFormat(cmd)
+	if err != nil {
+		return err
+	}
+	return tools.Check(targets, tools.BuildOptions{ConfigPath: configPath, RulesDir: rulesDir, LogFormat: logFormat})
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+	rulesDir, _ := cmd.Flags().GetString("rules-dir")
+	logFormat, err := getLogFormat(cmd)
+	if err != nil {
+		return err
+	}
+	return tools.Validate(tools.BuildOptions{ConfigPath: configPath, RulesDir: rulesDir, LogFormat: logFormat})
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+	rulesDir, _ := cmd.Flags().GetString("rules-dir")
+	logFormat, err := getLogFormat(cmd)
+	if err != nil {
+		return err
+	}
+	return tools.List(tools.BuildOptions{ConfigPath: configPath, RulesDir: rulesDir, LogFormat: logFormat})
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+	rulesDir, _ := cmd.Flags().GetString("rules-dir")
+	logFormat, err := getLogFormat(cmd)
+	if err != nil {
+		return err
+	}
+	return tools.Lint(tools.BuildOptions{ConfigPath: configPath, RulesDir: rulesDir, LogFormat: logFormat})
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	targets, _ := cmd.Flags().GetStringSlice("target")
+	configPath, _ := cmd.Flags().GetString("config")
+	rulesDir, _ := cmd.Flags().GetString("rules-dir")
+	addr, _ := cmd.Flags().GetString("addr")
+	targets, err := tools.ExpandToolPatterns(targets)
+	if err != nil {
+		return fmt.Errorf("--target: %w", err)
+	}
+	logFormat, err := getLogFormat(cmd)
+	if err != nil {
+		return err
+	}
+	return tools.Serve(targets, addr, tools.BuildOptions{ConfigPath: configPath, RulesDir: rulesDir, LogFormat: logFormat})
+}
+
+func runBundle(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+	rulesDir, _ := cmd.Flags().GetString("rules-dir")
+	output, _ := cmd.Flags().GetString("output")
+	extract, _ := cmd.Flags().GetString("extract")
+	force, _ := cmd.Flags().GetBool("force")
+	opts := tools.BuildOptions{ConfigPath: configPath, RulesDir: rulesDir}
+	if extract != "" {
+		return tools.ExtractBundle(extract, force, opts)
+	}
+	if force {
+		return fmt.Errorf("--force can only be used with --extract")
+	}
+	return tools.Bundle(output, opts)
+}
+
+func runOrphans(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+	rulesDir, _ := cmd.Flags().GetString("rules-dir")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	outputLayout, _ := cmd.Flags().GetString("output-layout")
+	if outputLayout != tools.LayoutMirror && outputLayout != tools.LayoutFlat {
+		return fmt.Errorf("--output-layout must be %s or %s, got %q", tools.LayoutMirror, tools.LayoutFlat, outputLayout)
+	}
+	logFormat, err := getLogFormat(cmd)
+	if err != nil {
+		return err
+	}
+	return tools.Orphans(tools.BuildOptions{
+		ConfigPath:   configPath,
+		RulesDir:     rulesDir,
+		OutputDir:    outputDir,
+		OutputLayout: outputLayout,
+		LogFormat:    logFormat,
+	})
+}
+
+// excludeTools removes the --exclude-target tools from targets, or from all
+// tools when no targets are given
+func excludeTools(targets, excluded []string) ([]string, error) {
+	if len(excluded) == 0 {
+		return targets, nil
+	}
+	excluded, err := tools.ExpandToolPatterns(excluded)
+	if err != nil {
+		return nil, fmt.Errorf("--exclude-target: %w", err)
+	}
+	skip := map[string]bool{}
+	for _, name := range excluded {
+		if !tools.IsKnownTool(name) {
+			return nil, fmt.Errorf("--exclude-target: unknown tool: %s (valid tools: %s)", name, strings.Join(tools.ToolNames, ", "))
+		}
+		skip[name] = true
+	}
+
+	if len(targets) == 0 {
+		targets = tools.ToolNames
+	}
+	remaining := []string{}
+	for _, target := range targets {
+		if !skip[target] {
+			remaining = append(remaining, target)
+		}
+	}
+	if len(remaining) == 0 {
+		return nil, fmt.Errorf("--exclude-target leaves no AI tools to build")
+	}
+	return remaining, nil
+}
+
+// getLogFormat returns the --log-format flag, checking it's a known format
+func getLogFormat(cmd *cobra.Command) (string, error) {
+	logFormat, _ := cmd.Flags().GetString("log-format")
+	if !slices.Contains(tools.LogFormats, logFormat) {
+		return "", fmt.Errorf("--log-format must be one of %s, got %q", strings.Join(tools.LogFormats, ", "), logFormat)
+	}
+	return logFormat, nil
+}
+
+func completeToolNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return tools.ToolNames, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeToolDirs completes the tool name of a --tool-dir tool=dir value
+func completeToolDirs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if strings.Contains(toComplete, "=") {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	}
+	names := make([]string, 0, len(tools.ToolNames))
+	for _, name := range tools.ToolNames {
+		names = append(names, name+"=")
+	}
+	return names, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeToolVersions completes a --tool-version tool=version value for the
+// tools with several output formats
+func completeToolVersions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	completions := []string{}
+	for _, name := range tools.ToolNames {
+		for _, version := range tools.ToolVersions(name) {
+			completions = append(completions, name+"="+version)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// startProfiling starts writing a CPU profile and an execution trace to the
+// given files, either of which may be empty. The returned function stops both
+// and must be called for the files to be complete.
+func startProfiling(profilePath, tracePath string) (func(), error) {
+	stops := []func(){}
+	stop := func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+
+	if profilePath != "" {
+		f, err := os.Create(profilePath)
+		if err != nil {
+			return nil, fmt.Errorf("--profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("--profile: %w", err)
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			f.Close()
+		})
+	}
+
+	if tracePath != "" {
+		f, err := os.Create(tracePath)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("--trace: %w", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			stop()
+			return nil, fmt.Errorf("--trace: %w", err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			f.Close()
+		})
+	}
+
+	return stop, nil
+}
+
+// parseMode parses octal permission bits such as 0644. 0000 is rejected, as
+// nothing could read the generated files, and as the build options use 0 for
+// the default permissions.
+func parseMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid permissions %q, expected octal such as 0644", value)
+	}
+	if mode == 0 {
+		return 0, fmt.Errorf("permissions %q would make generated files unreadable", value)
+	}
+	return os.FileMode(mode), nil
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{value: "0644", want: 0644},
+		{value: "755", want: 0755},
+		{value: "0600", want: 0600},
+		{value: "0000", wantErr: true},
+		{value: "0", wantErr: true},
+		{value: "01777", wantErr: true},
+		{value: "0688", wantErr: true},
+		{value: "rw-r--r--", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseMode(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseMode(%q) = %v, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseMode(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}